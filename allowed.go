@@ -4,63 +4,240 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/parsers"
 	"github.com/puzpuzpuz/xsync/v3"
 	"github.com/temoto/robotstxt"
 )
 
-var robotsMap = xsync.NewMapOf[string, *robotstxt.Group]()
+// https://www.rfc-editor.org/rfc/rfc9309.html#section-2.5
+const MAX_ROBOTS_BYTES = 500 * 1024
 
-func fetchRobots(u *url.URL, ctx context.Context) (*robotstxt.Group, error) {
+// https://www.rfc-editor.org/rfc/rfc9309.html#section-2.3.1.2
+const MAX_ROBOTS_REDIRECTS = 5
+
+// how long robots.txt is treated as disallowing everything after it was unreachable
+const ROBOTS_ERROR_TTL = 10 * time.Minute
+
+// how often to check if another instance finished fetching a shared robots.txt
+const ROBOTS_LOCK_POLL = 100 * time.Millisecond
+
+type robotsEntry struct {
+	group   *robotstxt.Group
+	expires time.Time
+	// wether robots.txt couldn't be fetched, so it disallows everything
+	unreachable bool
+	// urls from Sitemap lines
+	sitemaps []string
+}
+
+var robotsMap = xsync.NewMapOf[string, robotsEntry]()
+
+// robotsLoad is a robots.txt being loaded, which is waited on instead of loading it again
+type robotsLoad struct {
+	done  chan struct{}
+	entry robotsEntry
+}
+
+var robotsLoads = xsync.NewMapOf[string, *robotsLoad]()
+
+var robotsClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) > MAX_ROBOTS_REDIRECTS {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	},
+}
+
+func findGroup(robots *robotstxt.RobotsData) *robotstxt.Group {
+	return robots.FindGroup(common.Options.UserAgent)
+}
+
+// robotsEntryFromResponse parses a fetched robots.txt
+func robotsEntryFromResponse(resp robotsResponse, expires time.Time) (robotsEntry, error) {
+	status := resp.status
+	switch {
+	case status == 0, status >= 500:
+		// disallows everything until robots.txt is fetched again
+		// https://www.rfc-editor.org/rfc/rfc9309.html#section-2.3.1.4
+		robots, _ := robotstxt.FromStatusAndBytes(http.StatusServiceUnavailable, nil)
+		return robotsEntry{
+			group:       findGroup(robots),
+			expires:     expires,
+			unreachable: true,
+		}, nil
+	case status >= 300 && status < 400:
+		// too many redirects is treated as unavailable, which allows everything
+		// https://www.rfc-editor.org/rfc/rfc9309.html#section-2.3.1.2
+		status = http.StatusNotFound
+	}
+
+	// 2xx is parsed, and 4xx allows everything
+	robots, err := robotstxt.FromStatusAndBytes(status, resp.body)
+	if err != nil {
+		return robotsEntry{}, err
+	}
+
+	return robotsEntry{
+		group:    findGroup(robots),
+		expires:  expires,
+		sitemaps: robots.Sitemaps,
+	}, nil
+}
+
+func robotsTTL(resp robotsResponse) time.Duration {
+	if resp.status == 0 || resp.status >= 500 {
+		return ROBOTS_ERROR_TTL
+	}
+
+	return common.Options.RobotsTTL
+}
+
+func fetchRobots(u *url.URL, ctx context.Context) (robotsResponse, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprint(u.Scheme, "://", u.Host, "/robots.txt"), nil)
 	if err != nil {
-		return nil, err
+		return robotsResponse{}, err
 	}
 
 	req.Header.Add("User-Agent", common.Options.UserAgent)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := robotsClient.Do(req)
 	if err != nil {
-		return nil, err
+		return robotsResponse{}, err
 	}
 	defer resp.Body.Close()
-	robots, err := robotstxt.FromResponse(resp)
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MAX_ROBOTS_BYTES))
 	if err != nil {
-		return nil, err
+		return robotsResponse{}, err
 	}
 
-	group := robots.FindGroup(common.Options.UserAgent)
-
-	return group, nil
+	return robotsResponse{status: resp.StatusCode, body: body}, nil
 }
 
-func crawlAllowed(u *url.URL, ctx context.Context) error {
-	// @FIX ignore robots.txt data past 500kb (https://developers.google.com/search/docs/crawling-indexing/robots/robots_txt#file-format)
-	// @TODO use noindex maybe? (https://developers.google.com/search/docs/crawling-indexing/block-indexing)
-	if !common.Options.RespectRobots {
-		return nil
-	}
-
+// loadRobots fetches robots.txt, or waits for another instance to fetch it when it is shared
+func loadRobots(u *url.URL, ctx context.Context) (robotsResponse, time.Time) {
 	logger := common.LoggerFromContext(ctx)
 
-	robotsHost, _ := robotsMap.LoadOrCompute(u.Host, func() *robotstxt.Group {
+	fetch := func() robotsResponse {
 		start := time.Now()
-		g, err := fetchRobots(u, ctx)
+		resp, err := fetchRobots(u, ctx)
 		if err != nil {
 			logger.Error("unable to fetch robots.txt", "error", err, "duration", time.Since(start))
-			return &robotstxt.Group{}
+			return robotsResponse{}
 		}
 
 		logger.Debug("fetched robots.txt", "duration", time.Since(start))
-		return g
-	})
+		return resp
+	}
+
+	if sharedRobots == nil {
+		resp := fetch()
+		return resp, time.Now().Add(robotsTTL(resp))
+	}
+
+	for {
+		resp, expires, ok, err := sharedRobots.Load(u.Host)
+		if err != nil {
+			logger.Warn("unable to load shared robots.txt", "error", err)
+			resp := fetch()
+			return resp, time.Now().Add(robotsTTL(resp))
+		}
+
+		if ok {
+			return resp, expires
+		}
+
+		locked, err := sharedRobots.Lock(u.Host)
+		if err != nil {
+			logger.Warn("unable to lock shared robots.txt", "error", err)
+		}
+
+		if locked || err != nil {
+			resp := fetch()
+			ttl := robotsTTL(resp)
+
+			err := sharedRobots.Store(u.Host, resp, ttl)
+			if err != nil {
+				logger.Warn("unable to store shared robots.txt", "error", err)
+			}
+
+			return resp, time.Now().Add(ttl)
+		}
+
+		// another instance is fetching it
+		select {
+		case <-ctx.Done():
+			return robotsResponse{}, time.Now().Add(ROBOTS_ERROR_TTL)
+		case <-time.After(ROBOTS_LOCK_POLL):
+		}
+	}
+}
+
+// hostRobots returns the robots.txt of a host, loading it if it isn't cached or has expired.
+// it is loaded outside of robotsMap, so hosts sharing a bucket don't wait on each other,
+// and only once at a time per host
+func hostRobots(u *url.URL, ctx context.Context) robotsEntry {
+	entry, ok := robotsMap.Load(u.Host)
+	if ok && time.Now().Before(entry.expires) {
+		return entry
+	}
+
+	load := &robotsLoad{done: make(chan struct{})}
+	if other, loading := robotsLoads.LoadOrStore(u.Host, load); loading {
+		select {
+		case <-other.done:
+			return other.entry
+		case <-ctx.Done():
+			entry, _ := robotsEntryFromResponse(robotsResponse{}, time.Now())
+			return entry
+		}
+	}
+
+	defer func() {
+		robotsLoads.Delete(u.Host)
+		close(load.done)
+	}()
+
+	// another load may have finished since robotsMap was checked
+	entry, ok = robotsMap.Load(u.Host)
+	if ok && time.Now().Before(entry.expires) {
+		load.entry = entry
+		return entry
+	}
+
+	entry, err := robotsEntryFromResponse(loadRobots(u, ctx))
+	if err != nil {
+		common.LoggerFromContext(ctx).Error("unable to parse robots.txt", "error", err)
+		entry, _ = robotsEntryFromResponse(robotsResponse{}, time.Now().Add(ROBOTS_ERROR_TTL))
+	}
+
+	robotsMap.Store(u.Host, entry)
+	load.entry = entry
+	return entry
+}
+
+func crawlAllowed(u *url.URL, ctx context.Context) error {
+	// @TODO use noindex maybe? (https://developers.google.com/search/docs/crawling-indexing/block-indexing)
+	if !common.Options.RespectRobots {
+		return nil
+	}
+
+	robotsHost := hostRobots(u, ctx)
+	if robotsHost.unreachable {
+		// retrying before robots.txt is fetched again would only fail the same way
+		return parsers.RetryableAfter(errors.New("robots.txt was unreachable"), time.Until(robotsHost.expires))
+	}
 
-	if !robotsHost.Test(u.String()) {
-		return errors.New("url was disalowed by robots")
+	// rules match against the path and query, not the whole url
+	if !robotsHost.group.Test(u.RequestURI()) {
+		return errors.New("url was disallowed by robots")
 	}
 
 	return nil
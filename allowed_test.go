@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+// redirectingRobots serves robots.txt after redirecting it hops times
+func redirectingRobots(hops int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/hop/1", http.StatusFound)
+	})
+	mux.HandleFunc("/hop/{n}", func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscan(r.PathValue("n"), &n)
+		if n < hops {
+			http.Redirect(w, r, fmt.Sprint("/hop/", n+1), http.StatusFound)
+			return
+		}
+
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestFetchRobotsRedirects(t *testing.T) {
+	common.Options = common.Default
+
+	tests := []struct {
+		hops   int
+		status int
+	}{
+		// https://www.rfc-editor.org/rfc/rfc9309.html#section-2.3.1.2
+		{MAX_ROBOTS_REDIRECTS, http.StatusOK},
+		{MAX_ROBOTS_REDIRECTS + 1, http.StatusFound},
+	}
+
+	for _, test := range tests {
+		server := redirectingRobots(test.hops)
+		u, _ := url.Parse(server.URL)
+
+		resp, err := fetchRobots(u, context.Background())
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.status != test.status {
+			t.Errorf("robots.txt after %v redirects responded with %v, want %v", test.hops, resp.status, test.status)
+		}
+	}
+}
+
+func TestRobotsEntryFromResponse(t *testing.T) {
+	common.Options = common.Default
+
+	disallow := []byte("User-agent: *\nDisallow: /private\n")
+	tests := []struct {
+		resp        robotsResponse
+		path        string
+		allowed     bool
+		unreachable bool
+	}{
+		{robotsResponse{status: http.StatusOK, body: disallow}, "/private?a=1", false, false},
+		{robotsResponse{status: http.StatusOK, body: disallow}, "/public", true, false},
+		// 4xx allows everything
+		{robotsResponse{status: http.StatusNotFound}, "/private", true, false},
+		// too many redirects is treated as unavailable
+		{robotsResponse{status: http.StatusFound}, "/private", true, false},
+		// 5xx and unreachable disallow everything, which crawlAllowed checks before the rules
+		{robotsResponse{status: http.StatusServiceUnavailable}, "", false, true},
+		{robotsResponse{}, "", false, true},
+	}
+
+	for _, test := range tests {
+		entry, err := robotsEntryFromResponse(test.resp, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if entry.unreachable != test.unreachable {
+			t.Errorf("status %v: unreachable = %v, want %v", test.resp.status, entry.unreachable, test.unreachable)
+		}
+
+		if test.unreachable {
+			continue
+		}
+
+		if allowed := entry.group.Test(test.path); allowed != test.allowed {
+			t.Errorf("status %v: %v allowed = %v, want %v", test.resp.status, test.path, allowed, test.allowed)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package common
 
 import (
+	"fmt"
+	"os"
 	"reflect"
 	"time"
 
@@ -19,17 +21,63 @@ func (l *logLevel) UnmarshalText(text []byte) error {
 }
 
 type OptionsStructure struct {
-	InitialPages        []string      `toml:"initial_pages"`
-	DataPath            string        `toml:"data_path"`
-	LogLevel            logLevel      `toml:"log_level"`
-	UserAgent           string        `toml:"user_agent"`
-	QueuePrioritization string        `toml:"queue_prioritization"`
-	Workers             int           `toml:"workers"`
-	BatchSize           int           `toml:"batch_size"`
-	Recover             bool          `toml:"recover"`
-	CrawlTimeout        time.Duration `toml:"crawl_timeout"`
-	DefaultCrawlDelay   time.Duration `toml:"default_crawl_delay"`
-	RespectRobots       bool          `toml:"respect_robots"`
+	InitialPages          []string                 `toml:"initial_pages"`
+	InitialSitemaps       []string                 `toml:"initial_sitemaps"`
+	DataPath              string                   `toml:"data_path"`
+	Output                string                   `toml:"output"`
+	SegmentMaxSize        int64                    `toml:"segment_max_size"`
+	WarcPrefix            string                   `toml:"warc_prefix"`
+	WarcMaxSize           int64                    `toml:"warc_max_size"`
+	Storage               string                   `toml:"storage"`
+	S3KeyLayout           string                   `toml:"s3_key_layout"`
+	S3PartSize            uint64                   `toml:"s3_part_size"`
+	S3MaxAttempts         int                      `toml:"s3_max_attempts"`
+	LogLevel              logLevel                 `toml:"log_level"`
+	UserAgent             string                   `toml:"user_agent"`
+	QueuePrioritization   string                   `toml:"queue_prioritization"`
+	QueuePriorityFormula  string                   `toml:"queue_priority_formula"`
+	Workers               int                      `toml:"workers"`
+	MaxInFlight           int                      `toml:"max_in_flight"`
+	FlushInterval         time.Duration            `toml:"flush_interval"`
+	Recover               bool                     `toml:"recover"`
+	CrawlTimeout          time.Duration            `toml:"crawl_timeout"`
+	DefaultCrawlDelay     time.Duration            `toml:"default_crawl_delay"`
+	CrawlDelayOverrides   map[string]time.Duration `toml:"crawl_delay_overrides"`
+	AdaptiveDelayFactor   float64                  `toml:"adaptive_delay_factor"`
+	MaxCrawlDelay         time.Duration            `toml:"max_crawl_delay"`
+	MaxConnectionsPerHost int                      `toml:"max_connections_per_host"`
+	LimitByIp             bool                     `toml:"limit_by_ip"`
+	RespectRobots         bool                     `toml:"respect_robots"`
+	RobotsTTL             time.Duration            `toml:"robots_ttl"`
+	RobotsCache           string                   `toml:"robots_cache"`
+	DedupIndex            string                   `toml:"dedup_index"`
+	RecordedHeaders       []string                 `toml:"recorded_headers"`
+	RedirectPolicy        string                   `toml:"redirect_policy"`
+	AllowedMimes          []string                 `toml:"allowed_mimes"`
+	MaxBodySize           int64                    `toml:"max_body_size"`
+	MaxBodySizes          map[string]int64         `toml:"max_body_sizes"`
+	DiscoverSitemaps      bool                     `toml:"discover_sitemaps"`
+	AllowedDomains        []string                 `toml:"allowed_domains"`
+	BlockedDomains        []string                 `toml:"blocked_domains"`
+	IncludePatterns       []string                 `toml:"include_patterns"`
+	ExcludePatterns       []string                 `toml:"exclude_patterns"`
+	AllowedSchemes        []string                 `toml:"allowed_schemes"`
+	AllowedPorts          []int                    `toml:"allowed_ports"`
+	MaxDepth              int                      `toml:"max_depth"`
+	StripQueryParams      []string                 `toml:"strip_query_params"`
+	Frontier              string                   `toml:"frontier"`
+	WorkerId              string                   `toml:"worker_id"`
+	LeaseDuration         time.Duration            `toml:"lease_duration"`
+	MaxAttempts           int                      `toml:"max_attempts"`
+	RetryBaseDelay        time.Duration            `toml:"retry_base_delay"`
+	RetryMaxDelay         time.Duration            `toml:"retry_max_delay"`
+	SeenFilter            string                   `toml:"seen_filter"`
+	SeenCapacity          int                      `toml:"seen_capacity"`
+	SeenFalsePositiveRate float64                  `toml:"seen_false_positive_rate"`
+	Recrawl               bool                     `toml:"recrawl"`
+	RecrawlInterval       time.Duration            `toml:"recrawl_interval"`
+	RecrawlMinInterval    time.Duration            `toml:"recrawl_min_interval"`
+	RecrawlMaxInterval    time.Duration            `toml:"recrawl_max_interval"`
 
 	ValkeyAddr string `toml:"services_valkey_addr"`
 
@@ -38,21 +86,106 @@ type OptionsStructure struct {
 
 	EnableMetrics      bool   `toml:"services_enable_metrics"`
 	PrometheusPushAddr string `toml:"services_prometheus_push_addr"`
+
+	S3Endpoint  string `toml:"services_s3_endpoint"`
+	S3Bucket    string `toml:"services_s3_bucket"`
+	S3Region    string `toml:"services_s3_region"`
+	S3AccessKey string `toml:"services_s3_access_key"`
+	S3SecretKey string `toml:"services_s3_secret_key"`
+	S3UseSSL    bool   `toml:"services_s3_use_ssl"`
 }
 
 var Options OptionsStructure
 var Default = OptionsStructure{
-	InitialPages:        []string{"https://arxiv.org"},
-	DataPath:            "data/",
-	LogLevel:            logLevel{Level: log.InfoLevel},
-	QueuePrioritization: "mean",
-	UserAgent:           "Mozilla/5.0 (compatible; Crawler/1.0; +http://www.google.com/bot.html)",
-	Workers:             50,
-	BatchSize:           100,
-	Recover:             true,
-	CrawlTimeout:        5 * time.Second,
-	DefaultCrawlDelay:   500 * time.Millisecond,
-	RespectRobots:       true,
+	InitialPages:          []string{"https://arxiv.org"},
+	InitialSitemaps:       []string{},
+	DataPath:              "data/",
+	Output:                "segments",
+	SegmentMaxSize:        256 * 1024 * 1024,
+	WarcPrefix:            "crawler",
+	WarcMaxSize:           1024 * 1024 * 1024,
+	Storage:               "local",
+	S3KeyLayout:           "{output}/{date}/{name}",
+	S3PartSize:            16 * 1024 * 1024,
+	S3MaxAttempts:         5,
+	LogLevel:              logLevel{Level: log.InfoLevel},
+	QueuePrioritization:   "depth",
+	QueuePriorityFormula:  "inlinks / (depth + 1)",
+	UserAgent:             "Mozilla/5.0 (compatible; Crawler/1.0; +http://www.google.com/bot.html)",
+	Workers:               50,
+	MaxInFlight:           100,
+	FlushInterval:         5 * time.Second,
+	Recover:               true,
+	CrawlTimeout:          5 * time.Second,
+	DefaultCrawlDelay:     500 * time.Millisecond,
+	CrawlDelayOverrides:   map[string]time.Duration{},
+	AdaptiveDelayFactor:   1,
+	MaxCrawlDelay:         30 * time.Second,
+	MaxConnectionsPerHost: 2,
+	LimitByIp:             false,
+	RespectRobots:         true,
+	RobotsTTL:             24 * time.Hour,
+	RobotsCache:           "frontier",
+	DedupIndex:            "frontier",
+	RecordedHeaders: []string{
+		"Content-Type",
+		"Content-Length",
+		"Content-Encoding",
+		"Content-Language",
+		"Cache-Control",
+		"Expires",
+		"ETag",
+		"Last-Modified",
+		"Server",
+		"X-Robots-Tag",
+		"Link",
+	},
+	RedirectPolicy: "same_host",
+	AllowedMimes: []string{
+		"text/html",
+		"text/plain",
+		"text/markdown",
+		"application/pdf",
+		"image/jpeg",
+		"image/png",
+		"image/webp",
+		"image/gif",
+	},
+	MaxBodySize: 10 * 1024 * 1024,
+	MaxBodySizes: map[string]int64{
+		"application/pdf": 50 * 1024 * 1024,
+	},
+	DiscoverSitemaps: false,
+	AllowedDomains:   []string{},
+	BlockedDomains:   []string{},
+	IncludePatterns:  []string{},
+	ExcludePatterns:  []string{`^https?://[^/]*wiki`},
+	AllowedSchemes:   []string{"http", "https"},
+	AllowedPorts:     []int{},
+	MaxDepth:         0,
+	StripQueryParams: []string{
+		"utm_*",
+		"fbclid",
+		"gclid",
+		"msclkid",
+		"jsessionid",
+		"phpsessid",
+		"aspsessionid*",
+		"sessionid",
+		"sid",
+	},
+	Frontier:              "valkey",
+	LeaseDuration:         5 * time.Minute,
+	MaxAttempts:           5,
+	RetryBaseDelay:        30 * time.Second,
+	RetryMaxDelay:         time.Hour,
+	SeenFilter:            "exact",
+	SeenCapacity:          10_000_000,
+	SeenFalsePositiveRate: 0.01,
+	Recrawl:               false,
+	RecrawlInterval:       24 * time.Hour,
+	RecrawlMinInterval:    time.Hour,
+	RecrawlMaxInterval:    30 * 24 * time.Hour,
 
 	ValkeyAddr: "localhost:6379",
 
@@ -61,6 +194,13 @@ var Default = OptionsStructure{
 
 	EnableMetrics:      false,
 	PrometheusPushAddr: ":9091",
+
+	S3Endpoint:  "localhost:9000",
+	S3Bucket:    "crawler",
+	S3Region:    "",
+	S3AccessKey: "crawler",
+	S3SecretKey: "crawlercrawler",
+	S3UseSSL:    false,
 }
 
 const OPTIONS_PATH = "options.toml"
@@ -82,5 +222,14 @@ func LoadOptions() (OptionsStructure, error) {
 		f.Set(reflect.ValueOf(Default).Field(i))
 	}
 
+	if Options.WorkerId == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return OptionsStructure{}, err
+		}
+
+		Options.WorkerId = fmt.Sprintf("%v-%v", hostname, os.Getpid())
+	}
+
 	return Options, nil
 }
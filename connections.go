@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/puzpuzpuz/xsync/v3"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+// how long a host's resolved ip is used before it is resolved again
+const RESOLVED_TTL = 5 * time.Minute
+
+type resolvedHost struct {
+	ip      string
+	expires time.Time
+}
+
+var resolvedHosts = xsync.NewMapOf[string, resolvedHost]()
+
+// resolveConnection groups urls by host, or by the host's resolved ip when limit_by_ip is enabled.
+// it's called before the fetch stage, so lookups don't block the limiter
+func resolveConnection(u *url.URL, ctx context.Context) string {
+	if !common.Options.LimitByIp {
+		return u.Host
+	}
+
+	hostname := u.Hostname()
+	resolved, ok := resolvedHosts.Load(hostname)
+	if ok && time.Now().Before(resolved.expires) {
+		return resolved.ip
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, hostname)
+	if err != nil || len(addrs) < 1 {
+		// failed lookups aren't cached, and the fetch will most likely fail the same way
+		return hostname
+	}
+
+	ip := addrs[0].IP.String()
+	resolvedHosts.Store(hostname, resolvedHost{ip: ip, expires: time.Now().Add(RESOLVED_TTL)})
+	return ip
+}
+
+// connectionKey returns the key resolved for an item's connection
+func connectionKey(data *crawlDataContext) string {
+	if data.connection == "" {
+		return data.url.Host
+	}
+
+	return data.connection
+}
@@ -2,99 +2,166 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
+	"errors"
 	"net/url"
-	"os"
-	"path"
-	"strings"
 	"time"
 
 	"github.com/charmbracelet/log"
-	"github.com/hashicorp/go-metrics"
-	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/frontier"
 	"github.com/CelestialCrafter/crawler/parsers"
+	"github.com/CelestialCrafter/crawler/parsers/basic"
 	"github.com/CelestialCrafter/crawler/pipeline"
 	pb "github.com/CelestialCrafter/crawler/protos"
+	"github.com/CelestialCrafter/crawler/storage"
 )
 
 type crawlDataContext struct {
-	ctx      context.Context
-	url      *url.URL
-	cancel   context.CancelFunc
+	ctx context.Context
+	url *url.URL
+	// where the document was fetched from, after following redirects
+	location *url.URL
+	depth    int
 	document pb.Document
+	// wether the page was unchanged since its last crawl, so it wasn't downloaded again
+	unchanged bool
+	// what the fetch stage limits connections by
+	connection string
+	// latest time the url can be fetched and acknowledged before its lease expires, zero if it has no lease
+	fetchBy time.Time
 }
 
-func crawlPipeline(parser parsers.Parser, batch []string) (newUrls []string) {
-	workers := common.Options.Workers
-	metricsEnabled := true
+func newCrawlDataContext(entry frontier.Entry) (*crawlDataContext, error) {
+	u, err := url.Parse(entry.Url)
+	if err != nil {
+		return nil, err
+	}
 
-	queue := make([]*crawlDataContext, len(batch))
-	for i, urlString := range batch {
-		logger := log.WithPrefix("crawler").With("url", urlString)
-		u, err := url.Parse(urlString)
-		if err != nil {
-			log.Warn("error parsing url", "error", u)
-			continue
-		}
+	metadata := new(pb.Metadata)
+	if entry.Validators.ETag != "" {
+		metadata.Etag = &entry.Validators.ETag
+	}
+	if entry.Validators.LastModified != "" {
+		metadata.LastModified = &entry.Validators.LastModified
+	}
 
-		queue[i] = &crawlDataContext{
-			ctx: context.WithValue(
-				context.Background(),
-				common.ContextLogger,
-				logger,
-			),
-			document: pb.Document{Url: urlString, Metadata: new(pb.Metadata)},
-			url:      u,
-		}
+	var fetchBy time.Time
+	if !entry.LeaseExpires.IsZero() {
+		fetchBy = entry.LeaseExpires.Add(-common.Options.CrawlTimeout - common.Options.FlushInterval)
 	}
 
-	input := pipeline.Gen(queue...)
+	logger := log.WithPrefix("crawler").With("url", entry.Url)
+	return &crawlDataContext{
+		ctx: context.WithValue(
+			context.Background(),
+			common.ContextLogger,
+			logger,
+		),
+		document: pb.Document{Url: entry.Url, Metadata: metadata},
+		url:      u,
+		location: u,
+		depth:    entry.Depth,
+		fetchBy:  fetchBy,
+	}, nil
+}
 
-	sleep := pipeline.Work(pipeline.WorkOptions[*crawlDataContext, *crawlDataContext]{
-		Input:          input,
-		Workers:        workers,
-		MetricsEnabled: metricsEnabled,
-		Name:           "sleep",
-		Process: func(data *crawlDataContext) (*crawlDataContext, error) {
-			sleepTillCrawlable(data.url)
-			return data, nil
-		},
-	})
+// crawlPipeline crawls items from input until it is closed.
+// failed items are still returned alongside their error,
+// and urls found outside of pages (such as in sitemaps) are passed to discovered.
+// sitemaps are fetched following the redirects follow allows, like the parser's fetches.
+// duplicate content is only looked up if index isn't nil
+func crawlPipeline(
+	parser parsers.Parser,
+	follow basic.FollowRedirect,
+	writer storage.Writer,
+	index contentIndex,
+	input <-chan pipeline.Result[*crawlDataContext],
+	discovered func([]frontier.Entry),
+) <-chan pipeline.Result[*crawlDataContext] {
+	workers := common.Options.Workers
+	metricsEnabled := true
+
+	// sitemaps share the fetch stage's connection limits
+	limiter := pipeline.NewLimiter(common.Options.MaxConnectionsPerHost)
+	sitemaps := newSitemapDiscovery(follow, limiter, discovered)
 
 	allowed := pipeline.Work(pipeline.WorkOptions[*crawlDataContext, *crawlDataContext]{
-		Input:          sleep,
+		Input:          input,
 		Workers:        workers,
 		MetricsEnabled: metricsEnabled,
 		Name:           "allowed",
 		Process: func(data *crawlDataContext) (*crawlDataContext, error) {
-			data.ctx, data.cancel = context.WithTimeout(context.Background(), common.Options.CrawlTimeout)
+			ctx, cancel := context.WithTimeout(data.ctx, common.Options.CrawlTimeout)
+			defer cancel()
 
-			err := crawlAllowed(data.url, data.ctx)
+			err := crawlAllowed(data.url, ctx)
 			if err != nil {
-				data.cancel()
-				return nil, err
+				return data, err
 			}
+
+			data.connection = resolveConnection(data.url, ctx)
+
+			sitemaps.discover(data)
 			return data, nil
 		},
 	})
 
+	// after robots.txt is fetched, so its Crawl-delay can be used
+	scheduled := pipeline.Delay(allowed, func(data *crawlDataContext) (time.Time, error) {
+		due, ok := reserveCrawl(data.url, data.fetchBy)
+		if !ok {
+			return due, &notDueError{due: due}
+		}
+
+		return due, nil
+	})
+
 	fetch := pipeline.Work(pipeline.WorkOptions[*crawlDataContext, *crawlDataContext]{
-		Input:          allowed,
+		Input:          scheduled,
 		Workers:        workers,
 		MetricsEnabled: metricsEnabled,
 		Name:           "fetch",
+		Limiter:        limiter,
+		Key:            connectionKey,
 		Process: func(data *crawlDataContext) (*crawlDataContext, error) {
-			err := parser.Fetch(&data.document, data.ctx)
-			data.cancel()
+			ctx, cancel := context.WithTimeout(data.ctx, common.Options.CrawlTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := parser.Fetch(&data.document, ctx)
+			recordLatency(data.url, time.Since(start))
+
+			data.unchanged = errors.Is(err, parsers.ErrNotModified)
+			if data.unchanged {
+				err = nil
+			}
+
+			var redirectErr *parsers.RedirectError
+			if errors.As(err, &redirectErr) {
+				data.document.RedirectTo = &redirectErr.Location
+				err = nil
+			}
+
+			if overloaded, retryAfter := parsers.IsOverloaded(err); overloaded {
+				backoffHost(data.url, retryAfter)
+			} else if err == nil {
+				decayHost(data.url)
+			}
 
 			if err != nil {
-				return nil, err
+				return data, err
 			}
 
 			data.document.Metadata.CrawledAt = timestamppb.New(time.Now())
+			if response := data.document.Metadata.Response; response != nil {
+				location, err := url.Parse(response.FinalUrl)
+				if err == nil {
+					data.location = location
+				}
+			}
+
 			return data, nil
 		},
 	})
@@ -105,76 +172,73 @@ func crawlPipeline(parser parsers.Parser, batch []string) (newUrls []string) {
 		MetricsEnabled: metricsEnabled,
 		Name:           "parse",
 		Process: func(data *crawlDataContext) (*crawlDataContext, error) {
-			err := parser.ParsePage(&data.document, data.url)
+			if data.unchanged || data.document.RedirectTo != nil {
+				return data, nil
+			}
+
+			// relative links are resolved against where the document was fetched from
+			err := parser.ParsePage(&data.document, data.location)
 			if err != nil {
-				return nil, err
+				return data, err
 			}
 
 			return data, nil
 		},
 	})
 
-	write := pipeline.Work(pipeline.WorkOptions[*crawlDataContext, *crawlDataContext]{
+	dedup := pipeline.Work(pipeline.WorkOptions[*crawlDataContext, *crawlDataContext]{
 		Input:          parse,
 		Workers:        workers,
 		MetricsEnabled: metricsEnabled,
-		Name:           "write",
+		Name:           "dedup",
 		Process: func(data *crawlDataContext) (*crawlDataContext, error) {
-			output, err := proto.Marshal(&data.document)
-			if err != nil {
-				return nil, err
+			if data.unchanged || data.document.RedirectTo != nil {
+				return data, nil
 			}
 
-			hostPath := path.Join(common.Options.DataPath, "crawled/", data.url.Host)
-
-			_, err = os.Stat(hostPath)
+			data.document.ContentHash = originalHash(data.document.Original)
+			if index == nil {
+				return data, nil
+			}
 
+			first, err := index.Claim(data.document.ContentHash, data.document.Url)
 			if err != nil {
-				if os.IsNotExist(err) {
-					err := os.Mkdir(hostPath, 0755)
-					if err != nil {
-						return nil, err
-					}
-				} else {
-					return nil, err
-				}
+				return data, err
 			}
 
-			data.url.Path = strings.TrimPrefix(data.url.Path, "/")
-
-			err = os.WriteFile(
-				path.Join(hostPath, base64.URLEncoding.EncodeToString([]byte(data.url.Path))+".pb"),
-				output,
-				0644,
-			)
-
-			if err != nil {
-				return nil, err
+			if first != data.document.Url {
+				data.document.DuplicateOf = &first
 			}
 
 			return data, nil
 		},
 	})
 
-	for result := range write {
-		if result.Err != nil {
-			log.Warn("error pipelining", "error", result.Err)
-			continue
-		}
+	write := pipeline.Work(pipeline.WorkOptions[*crawlDataContext, *crawlDataContext]{
+		Input:          dedup,
+		Workers:        workers,
+		MetricsEnabled: metricsEnabled,
+		Name:           "write",
+		Process: func(data *crawlDataContext) (*crawlDataContext, error) {
+			err := writer.Write(storage.Crawl{
+				Document:  &data.document,
+				Location:  data.location,
+				Depth:     data.depth,
+				Unchanged: data.unchanged,
+			})
+
+			// a claim without a stored copy would make later copies reference nothing
+			claimed := index != nil && data.document.ContentHash != "" && data.document.DuplicateOf == nil
+			if err != nil && claimed {
+				releaseErr := index.Release(data.document.ContentHash, data.document.Url)
+				if releaseErr != nil {
+					common.LoggerFromContext(data.ctx).Error("unable to release content hash", "error", releaseErr)
+				}
+			}
 
-		item := *result.Item
-		log.Info("pipeline result", "item", item.url.String())
-		metrics.IncrCounterWithLabels(
-			[]string{"crawled_count"},
-			1,
-			[]metrics.Label{{
-				Name:  "domain",
-				Value: item.url.Hostname(),
-			}},
-		)
-
-		newUrls = append(newUrls, item.document.Children...)
-	}
+			return data, err
+		},
+	})
 
-	return
+	return write
 }
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/frontier"
+	"github.com/CelestialCrafter/crawler/parsers"
+	"github.com/CelestialCrafter/crawler/parsers/basic"
+	"github.com/CelestialCrafter/crawler/pipeline"
+	"github.com/CelestialCrafter/crawler/scope"
+	"github.com/CelestialCrafter/crawler/storage"
+)
+
+// memoryWriter keeps written crawls by url
+type memoryWriter struct {
+	mu     sync.Mutex
+	crawls map[string]storage.Crawl
+}
+
+func (w *memoryWriter) Write(crawl storage.Crawl) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.crawls[crawl.Document.Url] = crawl
+	return nil
+}
+
+func (w *memoryWriter) Close() error {
+	return nil
+}
+
+func testSite() *httptest.Server {
+	page := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(body))
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	})
+	mux.HandleFunc("/{$}", page(`<html><body><a href="/a">a</a> <a href="/b?utm_source=x">b</a></body></html>`))
+	mux.HandleFunc("/a", page(`<html><body><p>same</p></body></html>`))
+	mux.HandleFunc("/b", page(`<html><body><p>same</p></body></html>`))
+	mux.HandleFunc("/private", page(`<html><body><p>private</p></body></html>`))
+
+	return httptest.NewServer(mux)
+}
+
+func TestCrawlPipeline(t *testing.T) {
+	common.Options = common.Default
+	common.Options.Workers = 2
+	common.Options.DefaultCrawlDelay = time.Millisecond
+
+	server := testSite()
+	defer server.Close()
+
+	sc, err := scope.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	follow, err := redirectPolicy(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{"/", "/a", "/b", "/private", "/missing"}
+	input := make(chan pipeline.Result[*crawlDataContext], len(paths))
+	for _, p := range paths {
+		data, err := newCrawlDataContext(frontier.Entry{Url: server.URL + p})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		input <- pipeline.Result[*crawlDataContext]{Item: &data}
+	}
+	close(input)
+
+	writer := &memoryWriter{crawls: make(map[string]storage.Crawl)}
+	output := crawlPipeline(basic.New(follow), follow, writer, newMemoryContentIndex(), input, func([]frontier.Entry) {})
+
+	results := make(map[string]pipeline.Result[*crawlDataContext])
+	for result := range output {
+		results[strings.TrimPrefix((*result.Item).document.Url, server.URL)] = result
+	}
+
+	if len(results) != len(paths) {
+		t.Fatalf("got %v results, want %v", len(results), len(paths))
+	}
+
+	root := results["/"]
+	if root.Err != nil {
+		t.Fatalf("crawling / failed: %v", root.Err)
+	}
+	if !slices.Contains((*root.Item).document.Children, server.URL+"/a") {
+		t.Errorf("children of / = %v, want them to contain /a", (*root.Item).document.Children)
+	}
+
+	// robots.txt is respected
+	if err := results["/private"].Err; err == nil || !strings.Contains(err.Error(), "disallowed by robots") {
+		t.Errorf("crawling /private returned %v, want it to be disallowed", err)
+	}
+
+	var statusErr *parsers.StatusError
+	missing := results["/missing"].Err
+	if !errors.As(missing, &statusErr) || statusErr.StatusCode != http.StatusNotFound || parsers.IsRetryable(missing) {
+		t.Errorf("crawling /missing returned %v, want a permanent 404", missing)
+	}
+
+	for _, p := range []string{"/", "/a", "/b"} {
+		if _, ok := writer.crawls[server.URL+p]; !ok {
+			t.Errorf("%v wasn't written", p)
+		}
+	}
+	for _, p := range []string{"/private", "/missing"} {
+		if _, ok := writer.crawls[server.URL+p]; ok {
+			t.Errorf("%v was written, but failed", p)
+		}
+	}
+
+	// only one of the pages with the same content is stored as a reference to the other
+	a, b := &(*results["/a"].Item).document, &(*results["/b"].Item).document
+	duplicates := 0
+	if a.DuplicateOf != nil && *a.DuplicateOf == b.Url {
+		duplicates++
+	}
+	if b.DuplicateOf != nil && *b.DuplicateOf == a.Url {
+		duplicates++
+	}
+	if duplicates != 1 {
+		t.Errorf("duplicate of /a = %v, duplicate of /b = %v, want one to reference the other", a.DuplicateOf, b.DuplicateOf)
+	}
+}
+
+func TestCrawlPipelineSlowHost(t *testing.T) {
+	common.Options = common.Default
+	common.Options.Workers = 1
+	common.Options.DefaultCrawlDelay = time.Millisecond
+
+	slow := http.NewServeMux()
+	slow.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nCrawl-delay: 1\n"))
+	})
+	slow.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	slowServer := httptest.NewServer(slow)
+	defer slowServer.Close()
+
+	fastServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fastServer.Close()
+
+	urls := []string{slowServer.URL + "/1", slowServer.URL + "/2", slowServer.URL + "/3", fastServer.URL + "/"}
+	input := make(chan pipeline.Result[*crawlDataContext], len(urls))
+	for _, u := range urls {
+		data, err := newCrawlDataContext(frontier.Entry{Url: u})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		input <- pipeline.Result[*crawlDataContext]{Item: &data}
+	}
+	close(input)
+
+	writer := &memoryWriter{crawls: make(map[string]storage.Crawl)}
+	output := crawlPipeline(basic.New(nil), nil, writer, nil, input, func([]frontier.Entry) {})
+
+	// the slow host's urls wait for its Crawl-delay without holding back the other host
+	order := make([]string, 0, len(urls))
+	for result := range output {
+		if result.Err != nil {
+			t.Errorf("crawling %v failed: %v", (*result.Item).document.Url, result.Err)
+		}
+		order = append(order, (*result.Item).document.Url)
+	}
+
+	if len(order) != len(urls) || order[len(order)-1] == fastServer.URL+"/" {
+		t.Errorf("order = %v, want the other host's url to be crawled before the slow host's last url", order)
+	}
+}
+
+func TestCrawlPipelineLeaseDeadline(t *testing.T) {
+	common.Options = common.Default
+	common.Options.Workers = 1
+	common.Options.CrawlTimeout = time.Second
+	common.Options.FlushInterval = 100 * time.Millisecond
+
+	slow := http.NewServeMux()
+	slow.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nCrawl-delay: 1\n"))
+	})
+	slow.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(slow)
+	defer server.Close()
+
+	// each url has to be fetched within 500ms, but the host is only due every second
+	expires := time.Now().Add(1600 * time.Millisecond)
+	input := make(chan pipeline.Result[*crawlDataContext], 2)
+	for _, p := range []string{"/1", "/2"} {
+		data, err := newCrawlDataContext(frontier.Entry{Url: server.URL + p, LeaseExpires: expires})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		input <- pipeline.Result[*crawlDataContext]{Item: &data}
+	}
+	close(input)
+
+	start := time.Now()
+	writer := &memoryWriter{crawls: make(map[string]storage.Crawl)}
+	output := crawlPipeline(basic.New(nil), nil, writer, nil, input, func([]frontier.Entry) {})
+
+	results := make(map[string]error)
+	for result := range output {
+		results[strings.TrimPrefix((*result.Item).document.Url, server.URL)] = result.Err
+	}
+
+	if err := results["/1"]; err != nil {
+		t.Errorf("crawling /1 failed: %v", err)
+	}
+
+	// the url that couldn't be fetched in time is handed back instead of waiting past its lease
+	var notDue *notDueError
+	if !errors.As(results["/2"], &notDue) {
+		t.Fatalf("crawling /2 returned %v, want it to be handed back", results["/2"])
+	}
+	if until := notDue.due.Sub(start); until < 500*time.Millisecond {
+		t.Errorf("/2 is due %v after the crawl started, want it after the host's Crawl-delay", until)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("crawl took %v, want /2 to be handed back without waiting for its host", elapsed)
+	}
+}
+
+func TestStreamDefersUrlsPastTheirLease(t *testing.T) {
+	common.Options = common.Default
+	common.Options.WorkerId = "test"
+	common.Options.Frontier = "memory"
+
+	front, err := frontier.NewMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = front.Seed([]frontier.Entry{{Url: "http://a.com/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch, err := front.NextBatch(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := newCrawlDataContext(batch[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newStream(front, nil)
+	s.claim()
+	due := time.Now().Add(time.Hour)
+	s.consume(pipeline.Result[*crawlDataContext]{Item: &data, Err: &notDueError{due: due}})
+	s.flush()
+
+	stats, err := front.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the url waits for its host in the frontier without using up an attempt
+	if stats.Delayed != 1 || stats.InFlight != 0 || stats.Dead != 0 {
+		t.Errorf("stats = %+v, want the url to be delayed", stats)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/puzpuzpuz/xsync/v3"
+	"github.com/valkey-io/valkey-go"
+)
+
+// contentIndex remembers the first url that each content hash was crawled at
+type contentIndex interface {
+	// Claim records u as the first copy of hash, returning the url of the first copy
+	Claim(hash string, u string) (first string, err error)
+	// Release forgets u as the first copy of hash, if it is, so the next copy takes its place
+	Release(hash string, u string) error
+	// Reset forgets every content hash, so a new crawl doesn't point at the last one's copies
+	Reset() error
+}
+
+// originalHash hashes the content of a document as it was downloaded
+func originalHash(original []byte) string {
+	hash := sha256.Sum256(original)
+	return hex.EncodeToString(hash[:])
+}
+
+type memoryContentIndex struct {
+	firsts *xsync.MapOf[string, string]
+}
+
+func newMemoryContentIndex() *memoryContentIndex {
+	return &memoryContentIndex{firsts: xsync.NewMapOf[string, string]()}
+}
+
+func (i *memoryContentIndex) Claim(hash string, u string) (string, error) {
+	first, _ := i.firsts.LoadOrStore(hash, u)
+	return first, nil
+}
+
+func (i *memoryContentIndex) Release(hash string, u string) error {
+	i.firsts.Compute(hash, func(first string, loaded bool) (string, bool) {
+		return first, !loaded || first == u
+	})
+	return nil
+}
+
+func (i *memoryContentIndex) Reset() error {
+	i.firsts.Clear()
+	return nil
+}
+
+// hash of content hashes to the first url they were crawled at,
+// kept in one key so it can be cleared along with the frontier
+const CONTENT_KEY = "content"
+
+// returns the first url of a content hash, storing the given url if there is none
+var claimScript = valkey.NewLuaScript(`
+if redis.call("HSETNX", KEYS[1], ARGV[1], ARGV[2]) == 1 then
+	return ARGV[2]
+end
+return redis.call("HGET", KEYS[1], ARGV[1])
+`)
+
+// deletes the first url of a content hash if it is the given url
+var releaseScript = valkey.NewLuaScript(`
+if redis.call("HGET", KEYS[1], ARGV[1]) == ARGV[2] then
+	redis.call("HDEL", KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// valkeyContentIndex shares content hashes between crawler instances
+type valkeyContentIndex struct {
+	vk valkey.Client
+}
+
+func newValkeyContentIndex(vk valkey.Client) *valkeyContentIndex {
+	return &valkeyContentIndex{vk: vk}
+}
+
+func (i *valkeyContentIndex) Claim(hash string, u string) (string, error) {
+	return claimScript.Exec(context.Background(), i.vk, []string{CONTENT_KEY}, []string{hash, u}).ToString()
+}
+
+func (i *valkeyContentIndex) Release(hash string, u string) error {
+	return releaseScript.Exec(context.Background(), i.vk, []string{CONTENT_KEY}, []string{hash, u}).Error()
+}
+
+func (i *valkeyContentIndex) Reset() error {
+	vk := i.vk
+	return vk.Do(context.Background(), vk.B().Del().Key(CONTENT_KEY).Build()).Error()
+}
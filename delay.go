@@ -1,43 +1,173 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/CelestialCrafter/crawler/common"
 	"github.com/puzpuzpuz/xsync/v3"
 )
 
-var crawlDelayMap = xsync.NewMapOf[string, time.Time]()
+const MIN_HOST_BACKOFF = time.Second
 
-func sleepTillCrawlable(u *url.URL) {
-	if common.Options.DefaultCrawlDelay == time.Second*0 {
-		return
+// weight of the newest response time in a host's average latency
+const LATENCY_SMOOTHING = 0.3
+
+type hostDelay struct {
+	// earliest time the host can be crawled again
+	next time.Time
+	// extra delay between crawls, which grows while the host is overloaded
+	backoff time.Duration
+	// moving average of the host's response time
+	latency time.Duration
+}
+
+var crawlDelayMap = xsync.NewMapOf[string, hostDelay]()
+
+// delayOverride finds the configured delay for a host or its closest parent domain
+func delayOverride(hostname string) (time.Duration, bool) {
+	for {
+		delay, ok := common.Options.CrawlDelayOverrides[hostname]
+		if ok {
+			return delay, true
+		}
+
+		_, parent, found := strings.Cut(hostname, ".")
+		if !found {
+			return 0, false
+		}
+		hostname = parent
 	}
+}
+
+// crawlDelay is the largest of the host's default or overridden delay, its robots.txt Crawl-delay,
+// its latency based delay, and its backoff, capped at max_crawl_delay
+func crawlDelay(u *url.URL, state hostDelay) time.Duration {
+	delay := common.Options.DefaultCrawlDelay
+	if override, ok := delayOverride(u.Hostname()); ok {
+		delay = override
+	}
+
+	if robots, ok := robotsMap.Load(u.Host); ok {
+		delay = max(delay, robots.group.CrawlDelay)
+	}
+
+	adaptive := time.Duration(float64(state.latency) * common.Options.AdaptiveDelayFactor)
+	delay = max(delay, adaptive, state.backoff)
+
+	return min(delay, common.Options.MaxCrawlDelay)
+}
 
-	var oldCrawlTime time.Time
+// notDueError is returned for urls whose host isn't due before they have to be fetched,
+// so they're handed back to the frontier until then instead of outliving their lease
+type notDueError struct {
+	due time.Time
+}
+
+func (e *notDueError) Error() string {
+	return fmt.Sprintf("host isn't due until %v", e.due.Format(time.RFC3339))
+}
+
+// reserveCrawl reserves the host's next crawl for u, returning when it is due.
+// it doesn't wait, so the pipeline can hold u while crawling other hosts.
+// if the crawl wouldn't be due by latest, nothing is reserved and ok is false
+func reserveCrawl(u *url.URL, latest time.Time) (due time.Time, ok bool) {
 	crawlDelayMap.Compute(
 		u.Host,
-		func(oldValue time.Time, loaded bool) (newValue time.Time, delete bool) {
-			delete = false
-			oldCrawlTime = oldValue
+		func(oldValue hostDelay, loaded bool) (newValue hostDelay, delete bool) {
+			newValue = oldValue
 
-			var startingPoint time.Time
-			now := time.Now()
-
-			if oldValue.After(now) {
-				startingPoint = oldValue
-			} else {
-				startingPoint = now
+			due = time.Now()
+			if oldValue.next.After(due) {
+				due = oldValue.next
 			}
 
-			newValue = startingPoint.Add(common.Options.DefaultCrawlDelay)
+			ok = latest.IsZero() || !due.After(latest)
+			if ok {
+				newValue.next = due.Add(crawlDelay(u, oldValue))
+			}
 
-			return
+			return newValue, !loaded && !ok
 		},
 	)
 
-	if time.Now().Before(oldCrawlTime) {
-		time.Sleep(time.Until(oldCrawlTime))
+	return due, ok
+}
+
+// waitCrawlable reserves the host's next crawl for u, and waits until it is due.
+// only used outside of the pipeline, where waiting doesn't take up a worker
+func waitCrawlable(u *url.URL, ctx context.Context) error {
+	due, _ := reserveCrawl(u, time.Time{})
+
+	timer := time.NewTimer(time.Until(due))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
+
+// backoffHost doubles the backoff of a host that responded that it is overloaded,
+// and waits at least retryAfter before it is crawled again
+func backoffHost(u *url.URL, retryAfter time.Duration) {
+	crawlDelayMap.Compute(
+		u.Host,
+		func(oldValue hostDelay, loaded bool) (newValue hostDelay, delete bool) {
+			newValue = oldValue
+			newValue.backoff = min(max(oldValue.backoff*2, MIN_HOST_BACKOFF), common.Options.MaxCrawlDelay)
+
+			wait := min(max(newValue.backoff, retryAfter), common.Options.MaxCrawlDelay)
+			next := time.Now().Add(wait)
+			if next.After(newValue.next) {
+				newValue.next = next
+			}
+
+			return newValue, false
+		},
+	)
+}
+
+// decayHost halves the backoff of a host after a successful crawl
+func decayHost(u *url.URL) {
+	crawlDelayMap.Compute(
+		u.Host,
+		func(oldValue hostDelay, loaded bool) (newValue hostDelay, delete bool) {
+			if !loaded {
+				return oldValue, true
+			}
+
+			newValue = oldValue
+			newValue.backoff /= 2
+			if newValue.backoff < MIN_HOST_BACKOFF {
+				newValue.backoff = 0
+			}
+
+			return newValue, false
+		},
+	)
+}
+
+// recordLatency adds a response time to the host's average latency
+func recordLatency(u *url.URL, latency time.Duration) {
+	crawlDelayMap.Compute(
+		u.Host,
+		func(oldValue hostDelay, loaded bool) (newValue hostDelay, delete bool) {
+			newValue = oldValue
+			if oldValue.latency == 0 {
+				newValue.latency = latency
+			} else {
+				newValue.latency = time.Duration(
+					LATENCY_SMOOTHING*float64(latency) + (1-LATENCY_SMOOTHING)*float64(oldValue.latency),
+				)
+			}
+
+			return newValue, false
+		},
+	)
+}
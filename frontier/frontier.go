@@ -0,0 +1,87 @@
+package frontier
+
+import "time"
+
+type Stats struct {
+	Queued   int64
+	InFlight int64
+	// urls waiting to be retried
+	Delayed int64
+	Crawled int64
+	// urls that failed permanently, or ran out of attempts
+	Dead int64
+	// crawled urls waiting to be recrawled
+	Scheduled int64
+}
+
+type Entry struct {
+	Url string
+	// amount of links followed from a seed url
+	Depth int
+	// optional, scheduling hints from a sitemap
+	Hints *Hints
+	// validators from the url's last crawl, set by NextBatch when it is recrawled
+	Validators Validators
+	// when the url's lease runs out and it goes back into the queue, set by NextBatch
+	LeaseExpires time.Time
+}
+
+// Validators make a recrawl conditional, so unchanged content isn't downloaded again
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+type Hints struct {
+	Priority float64
+	// zero if unknown
+	LastMod time.Time
+}
+
+type Done struct {
+	Url string
+	// hash of the crawled content, compared against the last crawl's to adapt the recrawl interval.
+	// empty if the url was unchanged since its last crawl
+	Hash       string
+	CrawledAt  time.Time
+	Validators Validators
+}
+
+type Failure struct {
+	Url string
+	Err error
+	// retryable failures are retried with exponential backoff until they run out of attempts
+	Retryable bool
+	// optional, retries wait until the later of this and the backoff
+	NotBefore time.Time
+	// wether the url was handed back without being crawled,
+	// so it waits until NotBefore without using up an attempt
+	Deferred bool
+}
+
+// reason is what is stored alongside a dead url
+func (f Failure) reason() string {
+	if f.Err == nil {
+		return "unknown"
+	}
+
+	return f.Err.Error()
+}
+
+type Frontier interface {
+	// Seed clears the frontier and fills the queue with entries
+	Seed(entries []Entry) error
+	// NextBatch claims up to n of the highest priority urls from the queue.
+	// claimed urls are leased to this worker, and go back into the queue
+	// if they aren't marked as done before the lease expires
+	NextBatch(n int) ([]Entry, error)
+	// MarkDone acknowledges claimed urls, and moves them to the crawled set.
+	// when recrawling is enabled, they are also scheduled to be crawled again
+	MarkDone(done []Done) error
+	// Fail releases claimed urls that couldn't be crawled.
+	// failures that won't be retried are moved to the dead letter set alongside their error
+	Fail(failures []Failure) error
+	// Enqueue adds entries that have not been crawled yet to the queue
+	Enqueue(entries []Entry) error
+	Stats() (Stats, error)
+}
@@ -0,0 +1,388 @@
+package frontier
+
+import (
+	"container/heap"
+	"math"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/sitemap"
+)
+
+type memoryLease struct {
+	item     *memoryItem
+	worker   string
+	deadline float64
+}
+
+type memoryDelay struct {
+	item      *memoryItem
+	notBefore float64
+}
+
+type memoryItem struct {
+	url      string
+	host     string
+	depth    int
+	inlinks  int
+	priority float64
+	lastMod  float64
+	score    float64
+	index    int
+}
+
+// memoryQueue is a max heap of items by score
+type memoryQueue []*memoryItem
+
+func (q memoryQueue) Len() int           { return len(q) }
+func (q memoryQueue) Less(i, j int) bool { return q[i].score > q[j].score }
+func (q memoryQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *memoryQueue) Push(x any) {
+	item := x.(*memoryItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *memoryQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return item
+}
+
+// Memory is an in-process frontier that mirrors the valkey frontier,
+// useful for tests and for running without any outside services
+type Memory struct {
+	mu       sync.Mutex
+	queue    memoryQueue
+	items    map[string]*memoryItem
+	hosts    map[string]float64
+	leases   map[string]memoryLease
+	delayed  map[string]memoryDelay
+	attempts map[string]int
+	crawled  seenFilter
+	dead     map[string]string
+	recrawls map[string]memoryDelay
+	// recrawl interval in seconds, content hash, and validators of the last crawl
+	intervals  map[string]float64
+	hashes     map[string]string
+	validators map[string]Validators
+	logger     *log.Logger
+}
+
+func NewMemory() (*Memory, error) {
+	f := &Memory{logger: log.WithPrefix("frontier/memory")}
+	err := f.reset()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *Memory) reset() error {
+	crawled, err := newSeenFilter()
+	if err != nil {
+		return err
+	}
+
+	f.queue = make(memoryQueue, 0)
+	f.items = make(map[string]*memoryItem)
+	f.hosts = make(map[string]float64)
+	f.leases = make(map[string]memoryLease)
+	f.delayed = make(map[string]memoryDelay)
+	f.attempts = make(map[string]int)
+	f.crawled = crawled
+	f.dead = make(map[string]string)
+	f.recrawls = make(map[string]memoryDelay)
+	f.intervals = make(map[string]float64)
+	f.hashes = make(map[string]string)
+	f.validators = make(map[string]Validators)
+	return nil
+}
+
+func unixNow() float64 {
+	return float64(time.Now().UnixMilli()) / 1000
+}
+
+func (f *Memory) vars(item *memoryItem, now float64) scoreVars {
+	return scoreVars{
+		depth:    item.depth,
+		inlinks:  item.inlinks,
+		lastHit:  f.hosts[item.host],
+		now:      now,
+		priority: item.priority,
+		lastMod:  item.lastMod,
+	}
+}
+
+func (f *Memory) Seed(entries []Entry) error {
+	f.mu.Lock()
+	err := f.reset()
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return f.Enqueue(entries)
+}
+
+// reap moves items with expired leases back into the queue
+func (f *Memory) reap(score scorer, now float64) {
+	for u, lease := range f.leases {
+		if lease.deadline > now {
+			continue
+		}
+
+		delete(f.leases, u)
+		lease.item.score = score(f.vars(lease.item, now))
+		f.items[u] = lease.item
+		heap.Push(&f.queue, lease.item)
+	}
+}
+
+// promote moves retried or recrawled items that are due back into the queue
+func (f *Memory) promote(score scorer, now float64, delayed map[string]memoryDelay) {
+	for u, delay := range delayed {
+		if delay.notBefore > now {
+			continue
+		}
+
+		delete(delayed, u)
+		delay.item.score = score(f.vars(delay.item, now))
+		f.items[u] = delay.item
+		heap.Push(&f.queue, delay.item)
+	}
+}
+
+func (f *Memory) NextBatch(n int) ([]Entry, error) {
+	score, err := getScorer(common.Options.QueuePrioritization)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := unixNow()
+	f.reap(score, now)
+	f.promote(score, now, f.delayed)
+	f.promote(score, now, f.recrawls)
+
+	// scores go stale as hosts get hit, so oversample and rescore the candidates
+	candidates := make([]*memoryItem, 0, n*5)
+	for len(candidates) < n*5 && f.queue.Len() > 0 {
+		item := heap.Pop(&f.queue).(*memoryItem)
+		delete(f.items, item.url)
+		candidates = append(candidates, item)
+	}
+
+	batch := make([]Entry, 0, n)
+	for len(batch) < n && len(candidates) > 0 {
+		best := 0
+		bestScore := 0.0
+		for i, item := range candidates {
+			s := score(f.vars(item, now))
+			if i == 0 || s > bestScore {
+				best = i
+				bestScore = s
+			}
+		}
+
+		item := candidates[best]
+		candidates = append(candidates[:best], candidates[best+1:]...)
+
+		deadline := now + common.Options.LeaseDuration.Seconds()
+		f.hosts[item.host] = now
+		f.leases[item.url] = memoryLease{
+			item:     item,
+			worker:   common.Options.WorkerId,
+			deadline: deadline,
+		}
+		batch = append(batch, Entry{
+			Url:          item.url,
+			Depth:        item.depth,
+			Validators:   f.validators[item.url],
+			LeaseExpires: time.UnixMilli(int64(deadline * 1000)),
+		})
+	}
+
+	for _, item := range candidates {
+		item.score = score(f.vars(item, now))
+		f.items[item.url] = item
+		heap.Push(&f.queue, item)
+	}
+
+	return batch, nil
+}
+
+// scheduleRecrawl mirrors scheduleRecrawl in valkey-queue.lua
+func (f *Memory) scheduleRecrawl(item *memoryItem, done Done) {
+	interval, ok := f.intervals[done.Url]
+	unchanged := done.Hash == "" || f.hashes[done.Url] == done.Hash
+	interval = nextRecrawlInterval(interval, ok && unchanged, ok)
+
+	f.intervals[done.Url] = interval
+	if done.Hash != "" {
+		f.hashes[done.Url] = done.Hash
+	}
+	f.validators[done.Url] = done.Validators
+	f.recrawls[done.Url] = memoryDelay{
+		item:      item,
+		notBefore: float64(done.CrawledAt.UnixMilli())/1000 + interval,
+	}
+}
+
+func (f *Memory) MarkDone(done []Done) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, d := range done {
+		lease, ok := f.leases[d.Url]
+		if !ok || lease.worker != common.Options.WorkerId {
+			continue
+		}
+
+		delete(f.leases, d.Url)
+		delete(f.attempts, d.Url)
+		if !f.crawled.has(d.Url) {
+			f.crawled.add(d.Url)
+		}
+
+		if common.Options.Recrawl {
+			f.scheduleRecrawl(lease.item, d)
+		}
+	}
+
+	return nil
+}
+
+func (f *Memory) Fail(failures []Failure) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := unixNow()
+	for _, failure := range failures {
+		lease, ok := f.leases[failure.Url]
+		if !ok || lease.worker != common.Options.WorkerId {
+			continue
+		}
+		delete(f.leases, failure.Url)
+
+		if failure.Deferred {
+			f.delayed[failure.Url] = memoryDelay{
+				item:      lease.item,
+				notBefore: float64(failure.NotBefore.UnixMilli()) / 1000,
+			}
+			continue
+		}
+
+		f.attempts[failure.Url]++
+		attempts := f.attempts[failure.Url]
+		if failure.Retryable && attempts < common.Options.MaxAttempts {
+			delay := min(
+				common.Options.RetryBaseDelay.Seconds()*math.Pow(2, float64(attempts-1)),
+				common.Options.RetryMaxDelay.Seconds(),
+			)
+			notBefore := now + delay
+			if !failure.NotBefore.IsZero() {
+				notBefore = max(notBefore, float64(failure.NotBefore.UnixMilli())/1000)
+			}
+
+			f.delayed[failure.Url] = memoryDelay{item: lease.item, notBefore: notBefore}
+			continue
+		}
+
+		f.dead[failure.Url] = failure.reason()
+		delete(f.intervals, failure.Url)
+		delete(f.hashes, failure.Url)
+		delete(f.validators, failure.Url)
+	}
+
+	return nil
+}
+
+func (f *Memory) Enqueue(entries []Entry) error {
+	if len(entries) < 1 {
+		f.logger.Warn("no new urls")
+		return nil
+	}
+
+	score, err := getScorer(common.Options.QueuePrioritization)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := unixNow()
+	for _, entry := range entries {
+		if f.crawled.has(entry.Url) {
+			continue
+		}
+		if _, ok := f.leases[entry.Url]; ok {
+			continue
+		}
+		if _, ok := f.delayed[entry.Url]; ok {
+			continue
+		}
+		if _, ok := f.dead[entry.Url]; ok {
+			continue
+		}
+
+		u, err := url.Parse(entry.Url)
+		if err != nil || u.Host == "" {
+			continue
+		}
+
+		item, ok := f.items[entry.Url]
+		if !ok {
+			item = &memoryItem{
+				url:      entry.Url,
+				host:     u.Host,
+				depth:    entry.Depth,
+				priority: sitemap.DEFAULT_PRIORITY,
+			}
+			f.items[entry.Url] = item
+			heap.Push(&f.queue, item)
+		}
+
+		item.depth = min(item.depth, entry.Depth)
+		item.inlinks++
+		if entry.Hints != nil {
+			item.priority = entry.Hints.Priority
+			if !entry.Hints.LastMod.IsZero() {
+				item.lastMod = float64(entry.Hints.LastMod.Unix())
+			}
+		}
+		item.score = score(f.vars(item, now))
+		heap.Fix(&f.queue, item.index)
+	}
+
+	return nil
+}
+
+func (f *Memory) Stats() (Stats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return Stats{
+		Queued:    int64(f.queue.Len()),
+		InFlight:  int64(len(f.leases)),
+		Delayed:   int64(len(f.delayed)),
+		Crawled:   f.crawled.len(),
+		Dead:      int64(len(f.dead)),
+		Scheduled: int64(len(f.recrawls)),
+	}, nil
+}
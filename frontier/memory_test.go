@@ -0,0 +1,292 @@
+package frontier
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+func newTestMemory(t *testing.T) *Memory {
+	t.Helper()
+
+	common.Options = common.Default
+	common.Options.WorkerId = "test"
+	common.Options.RetryBaseDelay = 10 * time.Millisecond
+	common.Options.RetryMaxDelay = 10 * time.Millisecond
+	common.Options.MaxAttempts = 2
+
+	f, err := NewMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func checkStats(t *testing.T, f *Memory, want Stats) {
+	t.Helper()
+
+	stats, err := f.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func nextUrls(t *testing.T, f *Memory, n int) []string {
+	t.Helper()
+
+	batch, err := f.NextBatch(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urls := make([]string, len(batch))
+	for i, entry := range batch {
+		urls[i] = entry.Url
+	}
+
+	return urls
+}
+
+func TestMemorySeed(t *testing.T) {
+	f := newTestMemory(t)
+
+	err := f.Seed([]Entry{{Url: "http://a.com/"}, {Url: "http://b.com/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, f, Stats{Queued: 2})
+
+	nextUrls(t, f, 1)
+	checkStats(t, f, Stats{Queued: 1, InFlight: 1})
+
+	// seeding clears everything, including leases
+	err = f.Seed([]Entry{{Url: "http://c.com/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, f, Stats{Queued: 1})
+}
+
+func TestMemoryNextBatch(t *testing.T) {
+	f := newTestMemory(t)
+
+	err := f.Seed([]Entry{
+		{Url: "http://a.com/deep", Depth: 2},
+		{Url: "http://a.com/", Depth: 0},
+		{Url: "http://a.com/mid", Depth: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// depth prioritization crawls breadth first
+	urls := nextUrls(t, f, 2)
+	if len(urls) != 2 || urls[0] != "http://a.com/" || urls[1] != "http://a.com/mid" {
+		t.Errorf("first batch = %v, want the two shallowest urls", urls)
+	}
+	checkStats(t, f, Stats{Queued: 1, InFlight: 2})
+
+	urls = nextUrls(t, f, 2)
+	if len(urls) != 1 || urls[0] != "http://a.com/deep" {
+		t.Errorf("second batch = %v, want the remaining url", urls)
+	}
+
+	urls = nextUrls(t, f, 2)
+	if len(urls) != 0 {
+		t.Errorf("third batch = %v, want an empty batch", urls)
+	}
+}
+
+func TestMemoryMarkDone(t *testing.T) {
+	f := newTestMemory(t)
+
+	err := f.Seed([]Entry{{Url: "http://a.com/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextUrls(t, f, 1)
+
+	err = f.MarkDone([]Done{{Url: "http://a.com/", CrawledAt: time.Now()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, f, Stats{Crawled: 1})
+
+	// crawled urls aren't queued again
+	err = f.Enqueue([]Entry{{Url: "http://a.com/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, f, Stats{Crawled: 1})
+
+	// urls that aren't leased are ignored
+	err = f.MarkDone([]Done{{Url: "http://b.com/", CrawledAt: time.Now()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, f, Stats{Crawled: 1})
+}
+
+func TestMemoryFail(t *testing.T) {
+	f := newTestMemory(t)
+
+	err := f.Seed([]Entry{{Url: "http://a.com/retry"}, {Url: "http://a.com/fatal"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextUrls(t, f, 2)
+
+	err = f.Fail([]Failure{
+		{Url: "http://a.com/retry", Err: errors.New("timeout"), Retryable: true},
+		{Url: "http://a.com/fatal", Err: errors.New("not found")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, f, Stats{Delayed: 1, Dead: 1})
+
+	// retries wait for their backoff
+	urls := nextUrls(t, f, 1)
+	if len(urls) != 0 {
+		t.Errorf("batch before backoff = %v, want an empty batch", urls)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	urls = nextUrls(t, f, 1)
+	if len(urls) != 1 || urls[0] != "http://a.com/retry" {
+		t.Fatalf("batch after backoff = %v, want the retried url", urls)
+	}
+
+	// urls that run out of attempts are dead
+	err = f.Fail([]Failure{{Url: "http://a.com/retry", Err: errors.New("timeout"), Retryable: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, f, Stats{Dead: 2})
+}
+
+func TestMemoryFailNotBefore(t *testing.T) {
+	f := newTestMemory(t)
+
+	err := f.Seed([]Entry{{Url: "http://a.com/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextUrls(t, f, 1)
+
+	err = f.Fail([]Failure{{
+		Url:       "http://a.com/",
+		Err:       errors.New("too many requests"),
+		Retryable: true,
+		NotBefore: time.Now().Add(time.Hour),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the retry waits past its backoff
+	time.Sleep(20 * time.Millisecond)
+	urls := nextUrls(t, f, 1)
+	if len(urls) != 0 {
+		t.Errorf("batch before not before = %v, want an empty batch", urls)
+	}
+	checkStats(t, f, Stats{Delayed: 1})
+}
+
+func TestMemoryEnqueue(t *testing.T) {
+	f := newTestMemory(t)
+
+	err := f.Seed([]Entry{{Url: "http://a.com/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextUrls(t, f, 1)
+
+	err = f.Enqueue([]Entry{
+		// leased urls aren't queued again
+		{Url: "http://a.com/"},
+		{Url: "http://a.com/child", Depth: 2},
+		{Url: "http://a.com/child", Depth: 1},
+		// urls without a host are skipped
+		{Url: "/relative"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, f, Stats{Queued: 1, InFlight: 1})
+
+	batch, err := f.NextBatch(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// duplicates keep the shallowest depth
+	if len(batch) != 1 || batch[0].Url != "http://a.com/child" || batch[0].Depth != 1 {
+		t.Errorf("batch = %+v, want the child at depth 1", batch)
+	}
+}
+
+func TestMemoryFailWithoutError(t *testing.T) {
+	f := newTestMemory(t)
+
+	err := f.Seed([]Entry{{Url: "http://a.com/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextUrls(t, f, 1)
+
+	err = f.Fail([]Failure{{Url: "http://a.com/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, f, Stats{Dead: 1})
+
+	if reason := f.dead["http://a.com/"]; reason != "unknown" {
+		t.Errorf("dead reason = %q, want %q", reason, "unknown")
+	}
+}
+
+func TestMemoryFailDeferred(t *testing.T) {
+	f := newTestMemory(t)
+
+	err := f.Seed([]Entry{{Url: "http://a.com/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// deferring more often than max_attempts doesn't kill the url
+	for i := 0; i < common.Options.MaxAttempts+1; i++ {
+		batch, err := f.NextBatch(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(batch) != 1 {
+			t.Fatalf("deferral %v: batch = %v, want the deferred url", i, batch)
+		}
+		if until := time.Until(batch[0].LeaseExpires); until <= 0 || until > common.Options.LeaseDuration {
+			t.Errorf("lease expires in %v, want it within the lease duration", until)
+		}
+
+		err = f.Fail([]Failure{{
+			Url:       "http://a.com/",
+			Err:       errors.New("not due"),
+			NotBefore: time.Now(),
+			Deferred:  true,
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkStats(t, f, Stats{Delayed: 1})
+	}
+
+	if attempts := f.attempts["http://a.com/"]; attempts != 0 {
+		t.Errorf("deferrals used %v attempts, want none", attempts)
+	}
+}
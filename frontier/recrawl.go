@@ -0,0 +1,25 @@
+package frontier
+
+import "github.com/CelestialCrafter/crawler/common"
+
+// how much the recrawl interval grows when a url didn't change, or shrinks when it did
+const RECRAWL_FACTOR = 2
+
+// nextRecrawlInterval mirrors nextRecrawlInterval in valkey-queue.lua.
+// urls start at recrawl_interval, and the interval is halved when their content changed,
+// or doubled when it didn't
+func nextRecrawlInterval(interval float64, unchanged bool, crawledBefore bool) float64 {
+	switch {
+	case !crawledBefore:
+		interval = common.Options.RecrawlInterval.Seconds()
+	case unchanged:
+		interval *= RECRAWL_FACTOR
+	default:
+		interval /= RECRAWL_FACTOR
+	}
+
+	return max(
+		common.Options.RecrawlMinInterval.Seconds(),
+		min(interval, common.Options.RecrawlMaxInterval.Seconds()),
+	)
+}
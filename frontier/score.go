@@ -0,0 +1,50 @@
+package frontier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// scoreVars mirrors the variables given to scorers in valkey-queue.lua
+type scoreVars struct {
+	depth   int
+	inlinks int
+	// unix seconds
+	lastHit float64
+	now     float64
+	// sitemap hints
+	priority float64
+	// unix seconds, or 0 if unknown
+	lastMod float64
+}
+
+// higher scores are popped first
+type scorer func(vars scoreVars) float64
+
+func getScorer(method string) (scorer, error) {
+	switch strings.ToLower(method) {
+	case "depth":
+		// breadth first search
+		return func(vars scoreVars) float64 {
+			return -float64(vars.depth)
+		}, nil
+	case "inlinks":
+		return func(vars scoreVars) float64 {
+			return float64(vars.inlinks)
+		}, nil
+	case "recency":
+		// hosts that were hit the longest time ago first
+		return func(vars scoreVars) float64 {
+			return vars.now - vars.lastHit
+		}, nil
+	case "sitemap":
+		// highest sitemap priority first
+		return func(vars scoreVars) float64 {
+			return vars.priority
+		}, nil
+	case "formula":
+		return nil, fmt.Errorf("formula prioritization is only supported by the valkey frontier")
+	}
+
+	return nil, fmt.Errorf("unknown queue prioritization: %v", method)
+}
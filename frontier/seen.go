@@ -0,0 +1,95 @@
+package frontier
+
+import (
+	"fmt"
+
+	"github.com/bits-and-blooms/bloom/v3"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+// https://valkey.io/topics/strings/#limits
+const MAX_BLOOM_BITS = 1 << 32
+
+// seenFilter remembers which urls have been crawled.
+// the exact filter stores every url, while the bloom filter uses a fixed amount of memory,
+// at the cost of treating a fraction of unseen urls as already crawled
+type seenFilter interface {
+	add(url string)
+	has(url string) bool
+	len() int64
+}
+
+// bloomParameters returns the amount of bits and hashes needed for
+// seen_capacity urls at seen_false_positive_rate
+func bloomParameters() (uint, uint, error) {
+	capacity := common.Options.SeenCapacity
+	rate := common.Options.SeenFalsePositiveRate
+	if capacity < 1 {
+		return 0, 0, fmt.Errorf("seen capacity must be positive, got %d", capacity)
+	}
+	if rate <= 0 || rate >= 1 {
+		return 0, 0, fmt.Errorf("seen false positive rate must be between 0 and 1, got %v", rate)
+	}
+
+	bits, hashes := bloom.EstimateParameters(uint(capacity), rate)
+	if bits > MAX_BLOOM_BITS {
+		return 0, 0, fmt.Errorf(
+			"seen filter would need %d bits, over the max of %d",
+			bits,
+			uint64(MAX_BLOOM_BITS),
+		)
+	}
+
+	return bits, hashes, nil
+}
+
+func newSeenFilter() (seenFilter, error) {
+	switch common.Options.SeenFilter {
+	case "exact":
+		return make(exactSeen), nil
+	case "bloom":
+		bits, hashes, err := bloomParameters()
+		if err != nil {
+			return nil, err
+		}
+
+		return &bloomSeen{filter: bloom.New(bits, hashes)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown seen filter: %v", common.Options.SeenFilter)
+}
+
+type exactSeen map[string]struct{}
+
+func (s exactSeen) add(url string) {
+	s[url] = struct{}{}
+}
+
+func (s exactSeen) has(url string) bool {
+	_, ok := s[url]
+	return ok
+}
+
+func (s exactSeen) len() int64 {
+	return int64(len(s))
+}
+
+type bloomSeen struct {
+	filter *bloom.BloomFilter
+	// amount of urls added, including ones that were false positives
+	count int64
+}
+
+func (s *bloomSeen) add(url string) {
+	s.filter.AddString(url)
+	s.count++
+}
+
+func (s *bloomSeen) has(url string) bool {
+	return s.filter.TestString(url)
+}
+
+func (s *bloomSeen) len() int64 {
+	return s.count
+}
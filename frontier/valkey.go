@@ -0,0 +1,376 @@
+package frontier
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/valkey-io/valkey-go"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+const QUEUE_SCRIPT_PATH = "valkey-queue.lua"
+
+// how often expired leases are requeued, so urls claimed by a crashed worker
+// are crawled again even while no worker is claiming new batches
+const REAP_INTERVAL = 30 * time.Second
+
+type Valkey struct {
+	vk valkey.Client
+	// seen filter arguments passed to the script
+	seen   []string
+	logger *log.Logger
+}
+
+// seenArgs returns the seen filter's kind, bits, and hashes
+func seenArgs() ([]string, error) {
+	switch common.Options.SeenFilter {
+	case "exact":
+		return []string{"exact", "0", "0"}, nil
+	case "bloom":
+		bits, hashes, err := bloomParameters()
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{"bloom", fmt.Sprint(bits), fmt.Sprint(hashes)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown seen filter: %v", common.Options.SeenFilter)
+}
+
+// recrawlArgs returns the initial, min, and max recrawl intervals in seconds,
+// or zeros if recrawling is disabled
+func recrawlArgs() []string {
+	if !common.Options.Recrawl {
+		return []string{"0", "0", "0"}
+	}
+
+	return []string{
+		fmt.Sprint(common.Options.RecrawlInterval.Seconds()),
+		fmt.Sprint(common.Options.RecrawlMinInterval.Seconds()),
+		fmt.Sprint(common.Options.RecrawlMaxInterval.Seconds()),
+	}
+}
+
+func NewValkey(vk valkey.Client) (*Valkey, error) {
+	seen, err := seenArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	queueVkScript, err := os.ReadFile(QUEUE_SCRIPT_PATH)
+	if err != nil {
+		return nil, err
+	}
+
+	err = vk.Do(
+		context.Background(),
+		vk.
+			B().
+			FunctionLoad().
+			Replace().
+			FunctionCode(string(queueVkScript)).
+			Build(),
+	).Error()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Valkey{
+		vk:     vk,
+		seen:   seen,
+		logger: log.WithPrefix("frontier/valkey"),
+	}
+	go f.reapLeases()
+
+	return f, nil
+}
+
+// reapLeases periodically requeues urls whose leases expired
+func (f *Valkey) reapLeases() {
+	ticker := time.NewTicker(REAP_INTERVAL)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		vk := f.vk
+		reaped, err := vk.Do(context.Background(),
+			vk.
+				B().
+				Fcall().
+				Function("QUEUEREAP").
+				Numkeys(0).
+				Arg(common.Options.QueuePrioritization).
+				Arg(common.Options.QueuePriorityFormula).
+				Arg(now()).
+				Build(),
+		).AsInt64()
+		if err != nil {
+			f.logger.Warn("unable to reap expired leases", "error", err)
+			continue
+		}
+
+		if reaped > 0 {
+			f.logger.Info("requeued urls with expired leases", "count", reaped)
+		}
+	}
+}
+
+func now() string {
+	return fmt.Sprint(float64(time.Now().UnixMilli()) / 1000)
+}
+
+func (f *Valkey) Seed(entries []Entry) error {
+	vk := f.vk
+	err := vk.Do(
+		context.Background(),
+		vk.
+			B().
+			Del().
+			Key(
+				"queue",
+				"inflight",
+				"leases",
+				"delayed",
+				"attempts",
+				"crawled",
+				"seen",
+				"seencount",
+				"dead",
+				"recrawl",
+				"interval",
+				"contenthash",
+				"etag",
+				"lastmodified",
+				"depth",
+				"inlinks",
+				"hosts",
+				"priority",
+				"lastmod",
+			).
+			Build(),
+	).Error()
+	if err != nil {
+		return err
+	}
+
+	return f.Enqueue(entries)
+}
+
+func (f *Valkey) NextBatch(n int) ([]Entry, error) {
+	leased := time.Now()
+	vk := f.vk
+	batch, err := vk.Do(context.Background(),
+		vk.
+			B().
+			Fcall().
+			Function("QUEUEPOP").
+			Numkeys(0).
+			Arg(common.Options.QueuePrioritization).
+			Arg(common.Options.QueuePriorityFormula).
+			Arg(fmt.Sprint(float64(leased.UnixMilli())/1000)).
+			Arg(fmt.Sprint(n)).
+			Arg(common.Options.WorkerId).
+			Arg(fmt.Sprint(common.Options.LeaseDuration.Seconds())).
+			Build(),
+	).AsStrSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(batch)/4)
+	for i := 0; i+3 < len(batch); i += 4 {
+		depth, err := strconv.Atoi(batch[i+1])
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, Entry{
+			Url:   batch[i],
+			Depth: depth,
+			Validators: Validators{
+				ETag:         batch[i+2],
+				LastModified: batch[i+3],
+			},
+			LeaseExpires: leased.Add(common.Options.LeaseDuration),
+		})
+	}
+
+	return entries, nil
+}
+
+func (f *Valkey) MarkDone(done []Done) error {
+	if len(done) < 1 {
+		return nil
+	}
+
+	args := make([]string, 0, len(done)*5)
+	for _, d := range done {
+		crawledAt := float64(d.CrawledAt.UnixMilli()) / 1000
+		args = append(
+			args,
+			d.Url,
+			d.Hash,
+			fmt.Sprint(crawledAt),
+			d.Validators.ETag,
+			d.Validators.LastModified,
+		)
+	}
+
+	vk := f.vk
+	acked, err := vk.Do(context.Background(),
+		vk.
+			B().
+			Fcall().
+			Function("QUEUEACK").
+			Numkeys(0).
+			Arg(common.Options.WorkerId).
+			Arg(f.seen...).
+			Arg(recrawlArgs()...).
+			Arg(args...).
+			Build(),
+	).AsInt64()
+	if err != nil {
+		return err
+	}
+
+	if int(acked) < len(done) {
+		f.logger.Warn("urls were claimed by another worker after their lease expired", "count", len(done)-int(acked))
+	}
+
+	// @TODO use a domain label when you fix your metrics... stupid..
+
+	return nil
+}
+
+func (f *Valkey) Fail(failures []Failure) error {
+	if len(failures) < 1 {
+		return nil
+	}
+
+	args := make([]string, 0, len(failures)*5)
+	for _, failure := range failures {
+		retryable := "0"
+		if failure.Retryable {
+			retryable = "1"
+		}
+
+		deferred := "0"
+		if failure.Deferred {
+			deferred = "1"
+		}
+
+		notBefore := "0"
+		if !failure.NotBefore.IsZero() {
+			notBefore = fmt.Sprint(float64(failure.NotBefore.UnixMilli()) / 1000)
+		}
+
+		args = append(args, failure.Url, retryable, deferred, notBefore, failure.reason())
+	}
+
+	vk := f.vk
+	dead, err := vk.Do(context.Background(),
+		vk.
+			B().
+			Fcall().
+			Function("QUEUEFAIL").
+			Numkeys(0).
+			Arg(common.Options.WorkerId).
+			Arg(now()).
+			Arg(fmt.Sprint(common.Options.MaxAttempts)).
+			Arg(fmt.Sprint(common.Options.RetryBaseDelay.Seconds())).
+			Arg(fmt.Sprint(common.Options.RetryMaxDelay.Seconds())).
+			Arg(args...).
+			Build(),
+	).AsInt64()
+	if err != nil {
+		return err
+	}
+
+	if dead > 0 {
+		f.logger.Debug("moved urls to the dead letter set", "count", dead)
+	}
+
+	return nil
+}
+
+func (f *Valkey) Enqueue(entries []Entry) error {
+	if len(entries) < 1 {
+		f.logger.Warn("no new urls")
+		return nil
+	}
+
+	vk := f.vk
+	args := make([]string, 0, len(entries)*4)
+	for _, entry := range entries {
+		priority := ""
+		lastMod := ""
+		if entry.Hints != nil {
+			priority = fmt.Sprint(entry.Hints.Priority)
+			if !entry.Hints.LastMod.IsZero() {
+				lastMod = fmt.Sprint(entry.Hints.LastMod.Unix())
+			}
+		}
+
+		args = append(args, entry.Url, fmt.Sprint(entry.Depth), priority, lastMod)
+	}
+
+	// crawled and in-flight urls are filtered out by the script
+	return vk.Do(context.Background(),
+		vk.
+			B().
+			Fcall().
+			Function("QUEUEPUSH").
+			Numkeys(0).
+			Arg(common.Options.QueuePrioritization).
+			Arg(common.Options.QueuePriorityFormula).
+			Arg(now()).
+			Arg(f.seen...).
+			Arg(args...).
+			Build(),
+	).Error()
+}
+
+func (f *Valkey) Stats() (Stats, error) {
+	vk := f.vk
+	crawled := vk.B().Scard().Key("crawled").Build()
+	if common.Options.SeenFilter == "bloom" {
+		crawled = vk.B().Get().Key("seencount").Build()
+	}
+
+	resps := vk.DoMulti(
+		context.Background(),
+		vk.B().Zcard().Key("queue").Build(),
+		vk.B().Zcard().Key("inflight").Build(),
+		vk.B().Zcard().Key("delayed").Build(),
+		crawled,
+		vk.B().Hlen().Key("dead").Build(),
+		vk.B().Zcard().Key("recrawl").Build(),
+	)
+
+	counts := make([]int64, len(resps))
+	for i, resp := range resps {
+		count, err := resp.AsInt64()
+		if valkey.IsValkeyNil(err) {
+			continue
+		}
+		if err != nil {
+			return Stats{}, err
+		}
+
+		counts[i] = count
+	}
+
+	return Stats{
+		Queued:    counts[0],
+		InFlight:  counts[1],
+		Delayed:   counts[2],
+		Crawled:   counts[3],
+		Dead:      counts[4],
+		Scheduled: counts[5],
+	}, nil
+}
@@ -2,14 +2,17 @@ package main
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/hashicorp/go-metrics"
 	prometheus "github.com/hashicorp/go-metrics/prometheus"
-	"github.com/valkey-io/valkey-go"
 
 	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/frontier"
+	"github.com/CelestialCrafter/crawler/sitemap"
+	"github.com/CelestialCrafter/crawler/urlnorm"
 )
 
 func startMetrics() {
@@ -34,36 +37,74 @@ func startMetrics() {
 
 }
 
-func populateInitialUrls(vk valkey.Client) error {
-	if len(common.Options.InitialPages) < 1 {
+func initialEntries() ([]frontier.Entry, error) {
+	if len(common.Options.InitialSitemaps) < 1 {
+		entries := make([]frontier.Entry, len(common.Options.InitialPages))
+		for i, u := range common.Options.InitialPages {
+			entries[i] = frontier.Entry{Url: u}
+		}
+
+		return entries, nil
+	}
+
+	client := &http.Client{Timeout: common.Options.CrawlTimeout}
+	fetch := func(ctx context.Context, sitemapUrl string) (sitemap.Sitemap, error) {
+		return sitemap.Fetch(ctx, client, sitemapUrl)
+	}
+
+	urls, err := sitemap.Crawl(context.Background(), fetch, common.Options.InitialSitemaps, MAX_INITIAL_SITEMAPS)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("loaded initial sitemaps", "urls", len(urls))
+	return sitemapEntries(urls, 0), nil
+}
+
+// frontierStarted reports wether a crawl already has urls anywhere in the frontier
+func frontierStarted(stats frontier.Stats) bool {
+	return stats.Queued > 0 ||
+		stats.InFlight > 0 ||
+		stats.Delayed > 0 ||
+		stats.Scheduled > 0 ||
+		stats.Crawled > 0
+}
+
+func populateInitialUrls(front frontier.Frontier, index contentIndex) error {
+	if len(common.Options.InitialPages) < 1 && len(common.Options.InitialSitemaps) < 1 {
 		log.Warn("no urls in initial urls")
 		return nil
 	}
 
-	ctx := context.Background()
-	queueLength, err := vk.Do(ctx, vk.B().Scard().Key("queue").Build()).AsInt64()
+	stats, err := front.Stats()
 	if err != nil {
 		return err
 	}
 
-	if queueLength > 0 && common.Options.Recover {
+	// seeding clears the frontier, including other workers' leases and the recrawl schedule
+	if frontierStarted(stats) && common.Options.Recover {
 		return nil
 	}
 
-	for _, resp := range vk.DoMulti(
-		context.Background(),
-		vk.B().Del().Key("queue").Key("crawled").Build(),
-		vk.
-			B().
-			Sadd().
-			Key("queue").
-			Member(common.Options.InitialPages...).
-			Build(),
-	) {
-		err := resp.Error()
+	entries, err := initialEntries()
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+		entries[i].Url, err = urlnorm.NormalizeString(entry.Url)
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+
+	// the last crawl's copies may not be in this crawl's output
+	if index != nil {
+		err = index.Reset()
+		if err != nil {
+			return err
+		}
+	}
+
+	return front.Seed(entries)
 }
@@ -1,9 +1,7 @@
 package main
 
 import (
-	"context"
 	"os"
-	"path"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -12,7 +10,10 @@ import (
 	"github.com/valkey-io/valkey-go"
 
 	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/frontier"
 	"github.com/CelestialCrafter/crawler/parsers/basic"
+	"github.com/CelestialCrafter/crawler/scope"
+	"github.com/CelestialCrafter/crawler/storage"
 )
 
 func main() {
@@ -42,33 +43,80 @@ func main() {
 	startMetrics()
 
 	// valkey
-	vk, err := valkey.NewClient(valkey.ClientOption{
-		InitAddress: []string{common.Options.ValkeyAddr},
-	})
-	if err != nil {
-		log.Fatal("unable to connect to valkey", "error", err)
+	var vk valkey.Client
+	connectValkey := func() valkey.Client {
+		if vk != nil {
+			return vk
+		}
+
+		vk, err = valkey.NewClient(valkey.ClientOption{
+			InitAddress: []string{common.Options.ValkeyAddr},
+		})
+		if err != nil {
+			log.Fatal("unable to connect to valkey", "error", err)
+		}
+
+		return vk
 	}
+	defer func() {
+		if vk != nil {
+			vk.Close()
+		}
+	}()
 
-	queueVkScript, err := os.ReadFile("valkey-queue.lua")
-	if err != nil {
-		log.Fatal("unable to read valkey queue script", "error", err)
+	// frontier
+	var front frontier.Frontier
+	switch common.Options.Frontier {
+	case "valkey":
+		front, err = frontier.NewValkey(connectValkey())
+		if err != nil {
+			log.Fatal("unable to create valkey frontier", "error", err)
+		}
+	case "memory":
+		front, err = frontier.NewMemory()
+		if err != nil {
+			log.Fatal("unable to create memory frontier", "error", err)
+		}
+	default:
+		log.Fatal("unknown frontier", "frontier", common.Options.Frontier)
 	}
 
-	err = vk.Do(
-		context.Background(),
-		vk.
-			B().
-			FunctionLoad().
-			Replace().
-			FunctionCode(string(queueVkScript)).
-			Build(),
-	).Error()
+	// robots.txt
+	robotsCache := common.Options.RobotsCache
+	if robotsCache == "frontier" {
+		robotsCache = common.Options.Frontier
+	}
 
-	if err != nil {
-		log.Fatal("unable to load valkey queue script", "error", err)
+	switch robotsCache {
+	case "valkey":
+		sharedRobots = newValkeyRobotsCache(connectValkey())
+	case "memory":
+	default:
+		log.Fatal("unknown robots cache", "cache", common.Options.RobotsCache)
+	}
+
+	// deduplication
+	dedupIndex := common.Options.DedupIndex
+	if dedupIndex == "frontier" {
+		dedupIndex = common.Options.Frontier
+	}
+
+	var index contentIndex
+	switch dedupIndex {
+	case "valkey":
+		index = newValkeyContentIndex(connectValkey())
+	case "memory":
+		index = newMemoryContentIndex()
+	case "none":
+	default:
+		log.Fatal("unknown dedup index", "index", common.Options.DedupIndex)
 	}
 
-	defer vk.Close()
+	// scope
+	sc, err := scope.New()
+	if err != nil {
+		log.Fatal("unable to create scope", "error", err)
+	}
 
 	// i/o init
 	err = os.MkdirAll("data/", 0755)
@@ -76,45 +124,28 @@ func main() {
 		log.Fatal("unable to create data/ directory", "error", err)
 	}
 
-	err = populateInitialUrls(vk)
+	err = populateInitialUrls(front, index)
 	if err != nil {
 		log.Fatal("unable to populate database with initial urls", "error", err)
 	}
 
-	err = os.MkdirAll(path.Join(common.Options.DataPath, "crawled"), 0755)
+	// output
+	writer, err := storage.New()
 	if err != nil {
-		log.Fatal("unable to create crawled directory", "error", err)
+		log.Fatal("unable to create storage writer", "error", err)
 	}
 
-	// crawl loop
-	parser := basic.New()
-
-	var start time.Time
-	for {
-		start = time.Now()
-
-		batch, err := loadNewBatch(vk)
-		if err != nil {
-			log.Fatal("unable to load new batches", "error", err)
-		}
-
-		if len(batch) < 1 {
-			log.Warn("no new urls to be crawled; breaking.")
-			break
-		}
-
-		newUrls := crawlPipeline(parser, batch)
-
-		err = cleanupBatch(vk, batch)
-		if err != nil {
-			log.Fatal("unable to clean up batch", "error", err)
-		}
+	follow, err := redirectPolicy(sc)
+	if err != nil {
+		log.Fatal("unable to create redirect policy", "error", err)
+	}
 
-		err = writeNewQueue(vk, &newUrls)
-		if err != nil {
-			log.Fatal("unable to write aggregated data", "error", err)
-		}
+	// crawl loop
+	parser := basic.New(follow)
+	newStream(front, sc).run(parser, follow, writer, index)
 
-		log.Info("batch finished", "duration", time.Since(start))
+	err = writer.Close()
+	if err != nil {
+		log.Fatal("unable to close storage writer", "error", err)
 	}
 }
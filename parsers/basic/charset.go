@@ -0,0 +1,92 @@
+package basic
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// how much of a body is searched for a <meta charset> or http-equiv tag
+// https://html.spec.whatwg.org/multipage/parsing.html#prescan-a-byte-stream-to-determine-its-encoding
+const PRESCAN_BYTES = 1024
+
+// metaDeclared reports wether a body declares a known encoding in a <meta charset> or http-equiv tag
+func metaDeclared(body []byte) bool {
+	if len(body) > PRESCAN_BYTES {
+		body = body[:PRESCAN_BYTES]
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			return false
+		}
+
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		name, hasAttr := z.TagName()
+		if string(name) != "meta" {
+			continue
+		}
+
+		var label, content string
+		contentType := false
+		for hasAttr {
+			var key, value []byte
+			key, value, hasAttr = z.TagAttr()
+			switch string(key) {
+			case "charset":
+				label = string(value)
+			case "content":
+				content = string(value)
+			case "http-equiv":
+				contentType = strings.EqualFold(string(value), "content-type")
+			}
+		}
+
+		if label == "" && contentType {
+			_, params, err := mime.ParseMediaType(content)
+			if err == nil {
+				label = params["charset"]
+			}
+		}
+
+		if encoding, _ := charset.Lookup(label); encoding != nil {
+			return true
+		}
+	}
+}
+
+// detectEncoding returns the name of a body's encoding from its BOM, the Content-Type charset,
+// or a <meta charset> or http-equiv tag. bodies that don't declare one are treated as utf-8 if they're valid utf-8,
+// and otherwise have their encoding guessed from their content
+// https://html.spec.whatwg.org/multipage/parsing.html#determining-the-character-encoding
+func detectEncoding(body []byte, contentType string) string {
+	_, name, certain := charset.DetermineEncoding(body, contentType)
+	if certain || metaDeclared(body) {
+		return name
+	}
+
+	if utf8.Valid(body) {
+		return "utf-8"
+	}
+
+	return guessEncoding(body)
+}
+
+// utf8Reader transcodes a body from encoding to utf-8
+func utf8Reader(body []byte, encoding string) (io.Reader, error) {
+	if encoding == "" || strings.EqualFold(encoding, "utf-8") {
+		return bytes.NewReader(body), nil
+	}
+
+	return charset.NewReaderLabel(encoding, bytes.NewReader(body))
+}
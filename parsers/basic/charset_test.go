@@ -0,0 +1,91 @@
+package basic
+
+import (
+	"io"
+	"testing"
+
+	"golang.org/x/net/html/charset"
+)
+
+func encode(t *testing.T, name string, text string) []byte {
+	t.Helper()
+
+	encoding, _ := charset.Lookup(name)
+	encoded, err := encoding.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatalf("unable to encode %q as %v: %v", text, name, err)
+	}
+
+	return encoded
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		body        []byte
+		contentType string
+		want        string
+	}{
+		{[]byte("\xef\xbb\xbf<p>bom</p>"), "text/html; charset=windows-1251", "utf-8"},
+		{[]byte("<p>\xe9t\xe9</p>"), "text/html; charset=iso-8859-1", "windows-1252"},
+		// meta tags are honored even when the body is valid utf-8
+		{[]byte(`<meta charset="koi8-r"><p>plain</p>`), "text/html", "koi8-r"},
+		{[]byte(`<meta http-equiv="Content-Type" content="text/html; charset=shift_jis"><p>plain</p>`), "text/html", "shift_jis"},
+		// unknown labels are ignored
+		{[]byte(`<meta charset="made-up"><p>plain</p>`), "text/html", "utf-8"},
+		{[]byte("<p>é</p>"), "text/html", "utf-8"},
+		{[]byte("<p>plain</p>"), "", "utf-8"},
+	}
+
+	for _, test := range tests {
+		if encoding := detectEncoding(test.body, test.contentType); encoding != test.want {
+			t.Errorf("detectEncoding(%q, %q) = %q, want %q", test.body, test.contentType, encoding, test.want)
+		}
+	}
+}
+
+func TestGuessEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"windows-1252", "<p>Il était une fois une reine qui cousait près de sa fenêtre, où la neige tombait à gros flocons. Ça lui plaisait beaucoup.</p>"},
+		{"shift_jis", "<p>むかしむかし、ある所におじいさんとおばあさんが住んでいました。おじいさんは山へしば刈りに、おばあさんは川へ洗濯に行きました。</p>"},
+		{"euc-jp", "<p>むかしむかし、ある所におじいさんとおばあさんが住んでいました。おじいさんは山へしば刈りに、おばあさんは川へ洗濯に行きました。</p>"},
+		{"gbk", "<p>我们这个国家的人民在过去的一年里发生了很多事情，大家都说现在的生活比以前好多了，这是因为经济发展得很快。</p>"},
+		{"big5", "<p>我們這個國家的人民在過去的一年裡發生了很多事情，大家都說現在的生活比以前好多了，這是因為經濟發展得很快。</p>"},
+		{"euc-kr", "<p>옛날 옛적에 한 마을에 나무꾼이 살고 있었습니다. 그는 매일 산에 가서 나무를 하고 시장에 팔아 어머니를 모셨습니다.</p>"},
+		{"windows-1251", "<p>Жили-были дед и баба. Была у них курочка ряба. Снесла курочка яичко, не простое, а золотое. Дед бил, бил, не разбил.</p>"},
+		{"koi8-r", "<p>Жили-были дед и баба. Была у них курочка ряба. Снесла курочка яичко, не простое, а золотое. Дед бил, бил, не разбил.</p>"},
+		{"windows-1253", "<p>Μια φορά κι έναν καιρό ζούσε ένας βασιλιάς που είχε τρεις γιους. Ο μικρότερος ήταν ο πιο έξυπνος από όλους.</p>"},
+		{"windows-1255", "<p>היה היה פעם מלך שהיו לו שלושה בנים. הבן הצעיר היה החכם מכולם, והוא אהב ללכת ביער בכל בוקר.</p>"},
+		{"windows-1256", "<p>كان يا ما كان في قديم الزمان ملك له ثلاثة أبناء. وكان الابن الأصغر أذكى من إخوته، وكان يحب المشي في الغابة.</p>"},
+	}
+
+	for _, test := range tests {
+		body := encode(t, test.name, test.text)
+		if encoding := detectEncoding(body, "text/html"); encoding != test.name {
+			t.Errorf("detectEncoding() of %v text = %q, want %q", test.name, encoding, test.name)
+		}
+	}
+
+	// text that doesn't look like any language falls back to windows-1252
+	if encoding := guessEncoding([]byte("\xff\xfe\xfd\x80\x81")); encoding != "windows-1252" {
+		t.Errorf("guessEncoding() of noise = %q, want %q", encoding, "windows-1252")
+	}
+}
+
+func TestUtf8Reader(t *testing.T) {
+	text := "Жили-были дед и баба"
+	reader, err := utf8Reader(encode(t, "windows-1251", text), "windows-1251")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transcoded, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(transcoded) != text {
+		t.Errorf("utf8Reader() = %q, want %q", transcoded, text)
+	}
+}
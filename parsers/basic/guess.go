@@ -0,0 +1,152 @@
+package basic
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/unicode/rangetable"
+)
+
+// how much of a body is decoded when guessing its encoding
+const GUESS_BYTES = 16 * 1024
+
+// min score a guess needs to be used over windows-1252
+const MIN_GUESS_SCORE = 0.4
+
+// how much characters of the right script count towards a guess when they aren't common
+const SCRIPT_WEIGHT = 0.25
+
+// share of non-ascii characters that can be invalid before an encoding is ruled out
+const MAX_BAD_RATIO = 0.05
+
+// an encoding text may be in, and how text in it looks once decoded
+type encodingCandidate struct {
+	name   string
+	script *unicode.RangeTable
+	// frequent characters of the languages written in the encoding
+	common *unicode.RangeTable
+	// wether the script mixes non-ascii characters into words of ascii letters,
+	// instead of writing words entirely out of non-ascii characters
+	latin bool
+}
+
+// punctuation used alongside every cjk script
+var cjkPunctuation = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x3000, Hi: 0x303f, Stride: 1},
+		{Lo: 0xff01, Hi: 0xff5e, Stride: 1},
+	},
+}
+
+var fullwidthKana = rangetable.Merge(
+	unicode.Hiragana,
+	&unicode.RangeTable{R16: []unicode.Range16{{Lo: 0x30a0, Hi: 0x30ff, Stride: 1}}},
+)
+
+func commonRunes(s string) *unicode.RangeTable {
+	return rangetable.New([]rune(s)...)
+}
+
+var japanese = rangetable.Merge(unicode.Hiragana, unicode.Katakana, unicode.Han, cjkPunctuation)
+var chinese = rangetable.Merge(unicode.Han, cjkPunctuation)
+
+// frequent letters of russian, which is written in both windows-1251 and koi8-r
+var russian = commonRunes("оеаинтсрвлкм")
+
+// the same frequent characters, in simplified and traditional chinese
+var simplified = commonRunes("的一是不了在人有我他这个们中来上大为和国地到以说时要就出会可也你对生能而子那得于着下自之年过发后作里用道行所然家种事成方多经么去法学如都同现当没动面起看定天分还进好小部其些主样理心她本前开但因只从想实日")
+var traditional = commonRunes("的一是不了在人有我他這個們中來上大為和國地到以說時要就出會可也你對生能而子那得於著下自之年過發後作裡用道行所然家種事成方多經麼去法學如都同現當沒動面起看定天分還進好小部其些主樣理心她本前開但因只從想實日")
+
+// encodings which pages commonly don't declare, checked in order so earlier ones win ties.
+// windows-1252 is also the fallback when no guess is good enough
+var encodingCandidates = []encodingCandidate{
+	{name: "windows-1252", script: unicode.Latin, common: commonRunes("éèàçêùâôîëïüöäßñáíóúãõœæåøÿ"), latin: true},
+	{name: "shift_jis", script: japanese, common: fullwidthKana},
+	{name: "euc-jp", script: japanese, common: fullwidthKana},
+	{name: "gbk", script: chinese, common: simplified},
+	{name: "big5", script: chinese, common: traditional},
+	{name: "euc-kr", script: rangetable.Merge(unicode.Hangul, cjkPunctuation), common: commonRunes("이다의는에가을하고를지한기로사서도수어리자일인대부시나해그게있정으것들아보주적만전구상면제까요라니거우된")},
+	{name: "windows-1251", script: unicode.Cyrillic, common: russian},
+	{name: "koi8-r", script: unicode.Cyrillic, common: russian},
+	{name: "windows-1253", script: unicode.Greek, common: commonRunes("αεοιτνσςυκπμηρ")},
+	{name: "windows-1255", script: unicode.Hebrew, common: commonRunes("יוהלארמבתנש")},
+	{name: "windows-1256", script: unicode.Arabic, common: commonRunes("اليمنوةرعبتده")},
+}
+
+func isAsciiLetter(r rune) bool {
+	return r < utf8.RuneSelf && unicode.IsLetter(r)
+}
+
+// score decodes text with the candidate's encoding, and rates how much it looks like the candidate's languages.
+// common characters score 1 and other characters of the script score SCRIPT_WEIGHT,
+// relative to the amount of non-ascii characters.
+// it is zero if the text doesn't decode cleanly
+func (c encodingCandidate) score(text []byte) float64 {
+	encoding, _ := charset.Lookup(c.name)
+	decoded, err := encoding.NewDecoder().Bytes(text)
+	if err != nil {
+		return 0
+	}
+
+	runes := []rune(string(decoded))
+	// text may end in the middle of a character
+	if len(runes) > 0 && runes[len(runes)-1] == utf8.RuneError {
+		runes = runes[:len(runes)-1]
+	}
+
+	var nonAscii, bad, common, script float64
+	for i, r := range runes {
+		if r < utf8.RuneSelf {
+			continue
+		}
+		nonAscii++
+
+		if r == utf8.RuneError || (r >= 0x80 && r < 0xa0) || unicode.Is(unicode.Co, r) {
+			bad++
+			continue
+		}
+
+		if !unicode.Is(c.script, r) {
+			continue
+		}
+
+		// text decoded with the wrong encoding often puts the script in the wrong place
+		mixed := (i > 0 && isAsciiLetter(runes[i-1])) || (i+1 < len(runes) && isAsciiLetter(runes[i+1]))
+		if mixed != c.latin {
+			continue
+		}
+
+		if unicode.Is(c.common, r) {
+			common++
+		} else {
+			script++
+		}
+	}
+
+	if nonAscii == 0 || bad > nonAscii*MAX_BAD_RATIO {
+		return 0
+	}
+
+	return (common + script*SCRIPT_WEIGHT) / nonAscii
+}
+
+// guessEncoding guesses the encoding of text that didn't declare one from its content,
+// by decoding it with common encodings and picking the one whose result looks most like a language written in it
+func guessEncoding(text []byte) string {
+	if len(text) > GUESS_BYTES {
+		text = text[:GUESS_BYTES]
+	}
+
+	best := encodingCandidates[0].name
+	bestScore := MIN_GUESS_SCORE
+	for _, candidate := range encodingCandidates {
+		score := candidate.score(text)
+		if score > bestScore {
+			best = candidate.name
+			bestScore = score
+		}
+	}
+
+	return best
+}
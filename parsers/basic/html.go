@@ -1,7 +1,6 @@
 package basic
 
 import (
-	"bytes"
 	"errors"
 	"io"
 	"net/url"
@@ -10,6 +9,7 @@ import (
 	"strings"
 
 	pb "github.com/CelestialCrafter/crawler/protos"
+	"github.com/CelestialCrafter/crawler/urlnorm"
 	"golang.org/x/net/html"
 )
 
@@ -65,7 +65,12 @@ var whitespace = regexp.MustCompile(`\s+`)
 // note to self: don't put a context canceled check on this function
 // the time it takes to parse explodes
 func (p Basic) parseHtml(data *pb.Document, original *url.URL) error {
-	z := html.NewTokenizer(bytes.NewReader(data.Original))
+	reader, err := utf8Reader(data.Original, data.Metadata.GetEncoding())
+	if err != nil {
+		return err
+	}
+
+	z := html.NewTokenizer(reader)
 	useText := false
 	text := make([]byte, 0)
 	links := make([]string, 0)
@@ -108,21 +113,7 @@ func (p Basic) parseHtml(data *pb.Document, original *url.URL) error {
 					continue
 				}
 
-				u = original.ResolveReference(u)
-
-				if strings.Contains(u.Host, "wiki") {
-					continue
-				}
-
-				if !slices.Contains([]string{"http", "https"}, u.Scheme) {
-					p.logger.Debug("url did not match scheme whitelist", "url", u)
-					continue
-				}
-
-				u.RawQuery = ""
-				u.RawFragment = ""
-				u.Fragment = ""
-
+				u = urlnorm.Normalize(original.ResolveReference(u))
 				links = append(links, u.String())
 			} else if string(tn) == "meta" {
 				metadataAttributes := findAttributes(z, []string{"name", "property", "content"})
@@ -0,0 +1,65 @@
+package basic
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+// matchMime matches a mime type against "type/subtype", or "type/*"
+func matchMime(mime string, pattern string) bool {
+	prefix, wildcard := strings.CutSuffix(pattern, "/*")
+	if wildcard {
+		return strings.HasPrefix(mime, prefix+"/")
+	}
+
+	return mime == pattern
+}
+
+func mimeAllowed(mime string) bool {
+	return slices.ContainsFunc(common.Options.AllowedMimes, func(pattern string) bool {
+		return matchMime(mime, pattern)
+	})
+}
+
+// bodyLimit returns the max body size for a mime type,
+// preferring exact matches in max_body_sizes over wildcards
+func bodyLimit(mime string) int64 {
+	limit := common.Options.MaxBodySize
+	wildcard := false
+	for pattern, size := range common.Options.MaxBodySizes {
+		if pattern == mime {
+			return size
+		}
+
+		if !wildcard && matchMime(mime, pattern) {
+			limit = size
+			wildcard = true
+		}
+	}
+
+	return limit
+}
+
+// readBody reads up to limit bytes of a body,
+// and reports wether there was more to read
+func readBody(body io.Reader, contentLength int64, limit int64) ([]byte, bool, error) {
+	// abort before downloading the rest if the body is known to be too large
+	if contentLength > limit {
+		return nil, false, fmt.Errorf("body of %d bytes is over the limit of %d bytes", contentLength, limit)
+	}
+
+	bodyBytes, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if int64(len(bodyBytes)) > limit {
+		return bodyBytes[:limit], true, nil
+	}
+
+	return bodyBytes, false, nil
+}
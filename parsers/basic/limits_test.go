@@ -0,0 +1,83 @@
+package basic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+func TestMimeAllowed(t *testing.T) {
+	common.Options = common.Default
+	common.Options.AllowedMimes = []string{"text/html", "image/*"}
+
+	tests := []struct {
+		mime string
+		want bool
+	}{
+		{"text/html", true},
+		{"image/png", true},
+		{"text/plain", false},
+		{"imagex/png", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if allowed := mimeAllowed(test.mime); allowed != test.want {
+			t.Errorf("mimeAllowed(%q) = %v, want %v", test.mime, allowed, test.want)
+		}
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	common.Options = common.Default
+	common.Options.MaxBodySize = 10
+	common.Options.MaxBodySizes = map[string]int64{
+		"image/*":   20,
+		"image/png": 30,
+	}
+
+	tests := []struct {
+		mime string
+		want int64
+	}{
+		{"text/html", 10},
+		{"image/gif", 20},
+		// exact matches win over wildcards
+		{"image/png", 30},
+	}
+
+	for _, test := range tests {
+		if limit := bodyLimit(test.mime); limit != test.want {
+			t.Errorf("bodyLimit(%q) = %v, want %v", test.mime, limit, test.want)
+		}
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	tests := []struct {
+		body          string
+		contentLength int64
+		want          string
+		truncated     bool
+		fails         bool
+	}{
+		{"12345", 5, "12345", false, false},
+		// bodies without a length are cut off at the limit
+		{"1234567", -1, "12345", true, false},
+		// bodies known to be too large aren't read at all
+		{"1234567", 7, "", false, true},
+	}
+
+	for _, test := range tests {
+		body, truncated, err := readBody(strings.NewReader(test.body), test.contentLength, 5)
+		if (err != nil) != test.fails {
+			t.Errorf("readBody(%q, %v) returned %v, want failure %v", test.body, test.contentLength, err, test.fails)
+			continue
+		}
+
+		if string(body) != test.want || truncated != test.truncated {
+			t.Errorf("readBody(%q, %v) = %q, %v, want %q, %v", test.body, test.contentLength, body, truncated, test.want, test.truncated)
+		}
+	}
+}
@@ -1,69 +1,162 @@
 package basic
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/parsers"
 	pb "github.com/CelestialCrafter/crawler/protos"
 	"github.com/charmbracelet/log"
 )
 
-const MAX_MIME_BYTES = 24
+// https://www.rfc-editor.org/rfc/rfc9110.html#section-15.4-6
+const MAX_REDIRECTS = 10
+
+var ErrRedirectLoop = errors.New("redirect loop")
+
+// FollowRedirect decides wether a fetch should follow a redirect,
+// or stop so the redirect's target can be crawled on its own
+type FollowRedirect func(ctx context.Context, from *url.URL, to *url.URL) bool
 
 type Basic struct {
 	client *http.Client
 	logger *log.Logger
 }
 
-func New() Basic {
+// CheckRedirect returns an http.Client CheckRedirect which follows redirects that follow allows,
+// or every redirect if follow is nil, up to MAX_REDIRECTS
+func CheckRedirect(follow FollowRedirect) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > MAX_REDIRECTS {
+			return fmt.Errorf("stopped after %d redirects", MAX_REDIRECTS)
+		}
+
+		for _, previous := range via {
+			if previous.URL.String() == req.URL.String() {
+				return ErrRedirectLoop
+			}
+		}
+
+		if follow != nil && !follow(req.Context(), via[len(via)-1].URL, req.URL) {
+			return http.ErrUseLastResponse
+		}
+
+		return nil
+	}
+}
+
+// New creates a basic parser which follows redirects that follow allows, or every redirect if follow is nil
+func New(follow FollowRedirect) Basic {
 	return Basic{
-		client: &http.Client{},
+		client: &http.Client{CheckRedirect: CheckRedirect(follow)},
 		logger: log.WithPrefix("parser/basic"),
 	}
 }
 
 func (p Basic) Fetch(data *pb.Document, ctx context.Context) error {
+	trace, ctx := newFetchTrace(ctx)
 	req, err := http.NewRequestWithContext(ctx, "GET", data.Url, nil)
 	if err != nil {
 		return err
 	}
 
 	req.Header.Add("User-Agent", common.Options.UserAgent)
+	if etag := data.Metadata.GetEtag(); etag != "" {
+		req.Header.Add("If-None-Match", etag)
+	}
+	if lastModified := data.Metadata.GetLastModified(); lastModified != "" {
+		req.Header.Add("If-Modified-Since", lastModified)
+	}
 
 	res, err := p.client.Do(req)
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
+
+	data.Metadata.Response = trace.response(res)
+
+	if res.StatusCode == http.StatusNotModified {
+		// https://www.rfc-editor.org/rfc/rfc9110.html#section-15.4.5
+		setValidators(data.Metadata, res.Header, true)
+		return parsers.ErrNotModified
+	}
+
+	if location, err := res.Location(); err == nil && res.StatusCode >= 300 && res.StatusCode < 400 {
+		return &parsers.RedirectError{StatusCode: res.StatusCode, Location: location.String()}
+	}
 
 	if res.StatusCode >= 300 {
-		return fmt.Errorf("recieved status code: %d", res.StatusCode)
+		retryAfter, _ := parsers.ParseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+		return &parsers.StatusError{StatusCode: res.StatusCode, RetryAfter: retryAfter}
 	}
 
-	contentType := res.Header.Get("content-type")
-	if contentType == "" {
-		// https://www.rfc-editor.org/rfc/rfc9110.html#section-8.3-5
-		contentType = "application/octet-stream"
+	declared := mediaType(res.Header.Get("content-type"))
+	if declared != "" {
+		data.Metadata.DeclaredMime = &declared
 	}
 
-	bodyBytes, err := io.ReadAll(res.Body)
-	if err != nil {
+	// ambiguous types are checked again once they are sniffed
+	if !ambiguous(declared) && !mimeAllowed(declared) {
+		return fmt.Errorf("mime type is not allowed: %v", declared)
+	}
+
+	// the type is sniffed from the start of the body before the rest is downloaded,
+	// so disallowed types aren't downloaded, and the body is limited by the sniffed type
+	body := bufio.NewReaderSize(res.Body, SNIFF_BYTES)
+	head, err := body.Peek(SNIFF_BYTES)
+	if err != nil && !errors.Is(err, io.EOF) {
 		return err
 	}
 
-	res.Body.Close()
+	mime := sniffMime(declared, head, res.Request.URL)
+	if !mimeAllowed(mime) {
+		return fmt.Errorf("mime type is not allowed: %v", mime)
+	}
+
+	bodyBytes, truncated, err := readBody(body, res.ContentLength, bodyLimit(mime))
+	if err != nil {
+		return err
+	}
+	trace.read(data.Metadata.Response, len(bodyBytes))
 
-	mime := strings.Split(contentType, ";")[0]
 	data.Original = bodyBytes
+	data.Truncated = truncated
 	data.Metadata.Mime = mime
+	if strings.HasPrefix(mime, "text/") {
+		encoding := detectEncoding(bodyBytes, res.Header.Get("content-type"))
+		data.Metadata.Encoding = &encoding
+	}
+	setValidators(data.Metadata, res.Header, false)
 
 	return nil
 }
 
+// setValidators records the validators of a response for the next crawl.
+// 304 responses keep the previous validators for headers they didn't include
+func setValidators(metadata *pb.Metadata, header http.Header, notModified bool) {
+	set := func(field **string, name string) {
+		value := header.Get(name)
+		switch {
+		case value != "":
+			*field = &value
+		case !notModified:
+			*field = nil
+		}
+	}
+
+	set(&metadata.Etag, "ETag")
+	set(&metadata.LastModified, "Last-Modified")
+}
+
 func (p Basic) ParsePage(data *pb.Document, original *url.URL) error {
 	mime := data.Metadata.Mime
 
@@ -0,0 +1,58 @@
+package basic
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// https://www.rfc-editor.org/rfc/rfc9110.html#section-8.3-5
+const DEFAULT_MIME = "application/octet-stream"
+
+// how much of a body is sniffed, which is all http.DetectContentType looks at
+const SNIFF_BYTES = 512
+
+// declared types which servers commonly use for content they don't know the type of
+var ambiguousMimes = []string{
+	"",
+	DEFAULT_MIME,
+	"binary/octet-stream",
+	"application/unknown",
+	"text/plain",
+}
+
+func mediaType(contentType string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+}
+
+func ambiguous(declared string) bool {
+	return slices.Contains(ambiguousMimes, declared)
+}
+
+// sniffMime detects the mime type of a body from its declared type, magic bytes, and url extension.
+// the declared type is trusted unless it is ambiguous, or it is text while the body has a binary signature
+// https://mimesniff.spec.whatwg.org/
+func sniffMime(declared string, body []byte, u *url.URL) string {
+	// only finds binary signatures, html, xml, and text
+	detected := mediaType(http.DetectContentType(body))
+	extension := mediaType(mime.TypeByExtension(path.Ext(u.Path)))
+
+	signature := detected != DEFAULT_MIME && !strings.HasPrefix(detected, "text/")
+	switch {
+	case !ambiguous(declared) && strings.HasPrefix(declared, "text/") && signature:
+		return detected
+	case !ambiguous(declared):
+		return declared
+	case detected != DEFAULT_MIME && detected != "text/plain":
+		return detected
+	case extension != "":
+		return extension
+	case declared != "":
+		return declared
+	}
+
+	return detected
+}
@@ -0,0 +1,95 @@
+package basic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CelestialCrafter/crawler/common"
+	pb "github.com/CelestialCrafter/crawler/protos"
+)
+
+func TestSniffMime(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n"
+	tests := []struct {
+		declared string
+		body     string
+		path     string
+		want     string
+	}{
+		{"text/html", "<html></html>", "/", "text/html"},
+		// specific declared types are trusted
+		{"application/pdf", "<html></html>", "/", "application/pdf"},
+		// unless text is declared for a binary signature
+		{"text/html", png, "/", "image/png"},
+		// ambiguous types are sniffed
+		{"", "<html></html>", "/", "text/html"},
+		{DEFAULT_MIME, png, "/", "image/png"},
+		{"text/plain", "%PDF-1.7", "/", "application/pdf"},
+		// then detected from the url's extension
+		{DEFAULT_MIME, "plain words", "/page.html", "text/html"},
+		{"", "plain words", "/", "text/plain"},
+		{DEFAULT_MIME, "plain words", "/", DEFAULT_MIME},
+	}
+
+	for _, test := range tests {
+		u := &url.URL{Scheme: "http", Host: "a.com", Path: test.path}
+		if mime := sniffMime(test.declared, []byte(test.body), u); mime != test.want {
+			t.Errorf("sniffMime(%q, %q, %q) = %q, want %q", test.declared, test.body, test.path, mime, test.want)
+		}
+	}
+}
+
+func fetch(t *testing.T, u string, ctx context.Context) (*pb.Document, error) {
+	t.Helper()
+
+	document := &pb.Document{Url: u, Metadata: new(pb.Metadata)}
+	err := New(nil).Fetch(document, ctx)
+	return document, err
+}
+
+func TestFetchSniffsBeforeDownloading(t *testing.T) {
+	common.Options = common.Default
+
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("PK\x03\x04"))
+		w.Write(make([]byte, SNIFF_BYTES))
+		w.(http.Flusher).Flush()
+
+		// the rest of the body never arrives
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := fetch(t, server.URL+"/", ctx)
+	if err == nil || !strings.Contains(err.Error(), "not allowed: application/zip") {
+		t.Errorf("Fetch() = %v, want the sniffed zip to be rejected before the body is downloaded", err)
+	}
+}
+
+func TestFetchLimitsBySniffedMime(t *testing.T) {
+	common.Options = common.Default
+	common.Options.MaxBodySizes = map[string]int64{"text/html": 100}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// without a content type, only the sniffed type knows the limit
+		w.Header()["Content-Type"] = nil
+		w.Write([]byte("<html><body>" + strings.Repeat("a", 1000) + "</body></html>"))
+	}))
+	defer server.Close()
+
+	_, err := fetch(t, server.URL+"/", context.Background())
+	if err == nil || !strings.Contains(err.Error(), "over the limit of 100 bytes") {
+		t.Errorf("Fetch() = %v, want the body to be over the limit for html", err)
+	}
+}
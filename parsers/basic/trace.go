@@ -0,0 +1,106 @@
+package basic
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/CelestialCrafter/crawler/common"
+	pb "github.com/CelestialCrafter/crawler/protos"
+)
+
+// fetchTrace records the connection and timing of a fetch, across all of its redirects
+type fetchTrace struct {
+	mu         sync.Mutex
+	start      time.Time
+	remoteAddr net.Addr
+	firstByte  time.Time
+}
+
+func newFetchTrace(ctx context.Context) (*fetchTrace, context.Context) {
+	t := &fetchTrace{start: time.Now()}
+	return t, httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.remoteAddr = info.Conn.RemoteAddr()
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.firstByte = time.Now()
+			t.mu.Unlock()
+		},
+	})
+}
+
+// redirects returns the responses that redirected to res, in order
+func redirects(res *http.Response) []*pb.Redirect {
+	chain := make([]*pb.Redirect, 0)
+	for r := res.Request.Response; r != nil; r = r.Request.Response {
+		chain = append(chain, &pb.Redirect{
+			Url:    r.Request.URL.String(),
+			Status: int32(r.StatusCode),
+		})
+	}
+
+	slices.Reverse(chain)
+	return chain
+}
+
+func recordedHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for _, name := range common.Options.RecordedHeaders {
+		values := header.Values(name)
+		if len(values) > 0 {
+			headers[strings.ToLower(name)] = strings.Join(values, ", ")
+		}
+	}
+
+	return headers
+}
+
+// response describes res, which has been received but not read yet
+func (t *fetchTrace) response(res *http.Response) *pb.Response {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	response := &pb.Response{
+		Status:    int32(res.StatusCode),
+		Headers:   recordedHeaders(res.Header),
+		Redirects: redirects(res),
+		FinalUrl:  res.Request.URL.String(),
+		TotalTime: durationpb.New(time.Since(t.start)),
+	}
+
+	if t.remoteAddr != nil {
+		host, _, err := net.SplitHostPort(t.remoteAddr.String())
+		if err == nil {
+			response.RemoteIp = host
+		}
+	}
+
+	if !t.firstByte.IsZero() {
+		response.TimeToFirstByte = durationpb.New(t.firstByte.Sub(t.start))
+	}
+
+	if res.TLS != nil {
+		version := tls.VersionName(res.TLS.Version)
+		response.TlsVersion = &version
+	}
+
+	return response
+}
+
+// read records the body of the response being read
+func (t *fetchTrace) read(response *pb.Response, bytes int) {
+	response.Bytes = int64(bytes)
+	response.TotalTime = durationpb.New(time.Since(t.start))
+}
@@ -0,0 +1,145 @@
+package parsers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var retryableStatusCodes = []int{408, 425, 429, 500, 502, 503, 504}
+
+// ErrNotModified is returned by Fetch when a conditional request found the page unchanged
+var ErrNotModified = errors.New("not modified since last crawl")
+
+// RedirectError is returned by Fetch when a redirect wasn't followed, so its target can be crawled on its own
+type RedirectError struct {
+	StatusCode int
+	Location   string
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("redirected to %v with status code: %d", e.Location, e.StatusCode)
+}
+
+// StatusError is returned by Fetch when a page responds with an unsuccessful status code
+type StatusError struct {
+	StatusCode int
+	// zero if the response had no valid Retry-After header
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("received status code: %d", e.StatusCode)
+}
+
+type retryableError struct {
+	error
+	// zero if it can be retried with the usual backoff
+	after time.Duration
+}
+
+func (e retryableError) Unwrap() error {
+	return e.error
+}
+
+// Retryable marks err as an error that might not occur if attempted again later
+func Retryable(err error) error {
+	return retryableError{error: err}
+}
+
+// RetryableAfter marks err as an error that won't occur if attempted again once after has passed
+func RetryableAfter(err error, after time.Duration) error {
+	return retryableError{error: err, after: after}
+}
+
+// IsOverloaded reports whether a host responded to a crawl that failed with err
+// by asking to be crawled less often
+func IsOverloaded(err error) (overloaded bool, retryAfter time.Duration) {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false, 0
+	}
+
+	switch statusErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true, statusErr.RetryAfter
+	}
+
+	return false, 0
+}
+
+// RetryAfter returns how long to wait before a crawl that failed with err is retried,
+// either because the host asked to or the error won't clear before then, or zero if it can be retried with the usual backoff
+func RetryAfter(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+
+	var retryableErr retryableError
+	if errors.As(err, &retryableErr) {
+		return retryableErr.after
+	}
+
+	return 0
+}
+
+// ParseRetryAfter parses a Retry-After header in either its seconds or HTTP-date form
+// https://www.rfc-editor.org/rfc/rfc9110.html#section-10.2.3
+func ParseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseUint(header, 10, 32)
+	if err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(date.Sub(now), 0), true
+}
+
+// IsRetryable reports whether a crawl that failed with err might succeed if attempted again later
+func IsRetryable(err error) bool {
+	if errors.As(err, &retryableError{}) {
+		return true
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return slices.Contains(retryableStatusCodes, statusErr.StatusCode)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary
+	}
+
+	return false
+}
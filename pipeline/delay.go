@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"container/heap"
+	"time"
+)
+
+type delayedItem[I any] struct {
+	due  time.Time
+	item Result[I]
+}
+
+// delayQueue is a min heap of items by when they're due
+type delayQueue[I any] []delayedItem[I]
+
+func (q delayQueue[I]) Len() int           { return len(q) }
+func (q delayQueue[I]) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+func (q delayQueue[I]) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *delayQueue[I]) Push(x any) {
+	*q = append(*q, x.(delayedItem[I]))
+}
+
+func (q *delayQueue[I]) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// Delay holds each item until the time due returns for it, without taking up a worker,
+// so items that aren't due yet don't hold back the ones behind them.
+// failed items, and items that due fails for, are passed on right away
+func Delay[I any](input <-chan Result[I], due func(I) (time.Time, error)) <-chan Result[I] {
+	output := make(chan Result[I])
+
+	go func() {
+		defer close(output)
+
+		held := make(delayQueue[I], 0)
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+
+		for input != nil || held.Len() > 0 {
+			var wake <-chan time.Time
+			if held.Len() > 0 {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(time.Until(held[0].due))
+				wake = timer.C
+			}
+
+			select {
+			case item, ok := <-input:
+				if !ok {
+					input = nil
+					continue
+				}
+
+				if item.Err != nil {
+					output <- item
+					continue
+				}
+
+				at, err := due(*item.Item)
+				if err != nil {
+					output <- Result[I]{Err: err, Item: item.Item}
+					continue
+				}
+
+				heap.Push(&held, delayedItem[I]{due: at, item: item})
+			case <-wake:
+			}
+
+			now := time.Now()
+			for held.Len() > 0 && !held[0].due.After(now) {
+				output <- heap.Pop(&held).(delayedItem[I]).item
+			}
+		}
+	}()
+
+	return output
+}
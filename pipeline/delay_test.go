@@ -0,0 +1,51 @@
+package pipeline
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	start := time.Now()
+	dues := map[string]time.Duration{
+		"later": 100 * time.Millisecond,
+		"soon":  20 * time.Millisecond,
+		"now":   0,
+	}
+
+	input := Gen("later", "soon", "now", "broken")
+	output := Delay(input, func(item string) (time.Time, error) {
+		if item == "broken" {
+			return time.Time{}, errors.New("broken")
+		}
+
+		return start.Add(dues[item]), nil
+	})
+
+	order := make([]string, 0)
+	for result := range output {
+		if result.Err != nil {
+			order = append(order, "error")
+			continue
+		}
+
+		item := *result.Item
+		if elapsed := time.Since(start); elapsed < dues[item] {
+			t.Errorf("%v was passed on after %v, before it was due", item, elapsed)
+		}
+		order = append(order, item)
+	}
+
+	// items that aren't due don't hold back the ones behind them
+	want := []string{"now", "error", "soon", "later"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("order = %v, want %v", order, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+)
+
+// Limiter caps how many items with the same key are processed at once
+type Limiter struct {
+	mu     sync.Mutex
+	max    int
+	active map[string]int
+	// closed and replaced whenever a key is released, so everything waiting on the limiter tries again
+	released chan struct{}
+}
+
+func NewLimiter(max int) *Limiter {
+	return &Limiter{
+		max:      max,
+		active:   make(map[string]int),
+		released: make(chan struct{}),
+	}
+}
+
+// acquire tries to acquire key, returning a channel that is closed on the next release if it couldn't
+func (l *Limiter) acquire(key string) (ok bool, released <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active[key] >= l.max {
+		return false, l.released
+	}
+
+	l.active[key]++
+	return true, nil
+}
+
+func (l *Limiter) TryAcquire(key string) bool {
+	ok, _ := l.acquire(key)
+	return ok
+}
+
+// Acquire waits until key can be acquired, or ctx is done
+func (l *Limiter) Acquire(ctx context.Context, key string) error {
+	for {
+		ok, released := l.acquire(key)
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *Limiter) Release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active[key]--
+	if l.active[key] < 1 {
+		delete(l.active, key)
+	}
+
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// limit defers items whose key is saturated, so workers move on to other items instead of blocking.
+// keys may also be acquired outside of the pipeline, which wakes deferred items the same way when released
+func limit[I any, O any](opts WorkOptions[I, O]) (ready <-chan Result[I], release func(I)) {
+	output := make(chan Result[I])
+
+	release = func(item I) {
+		opts.Limiter.Release(opts.Key(item))
+	}
+
+	go func() {
+		defer close(output)
+
+		input := opts.Input
+		pending := make([]Result[I], 0)
+		// closed on the first release after the oldest failed acquire
+		var wake <-chan struct{}
+
+		tryAcquire := func(item Result[I]) bool {
+			ok, released := opts.Limiter.acquire(opts.Key(*item.Item))
+			if !ok && wake == nil {
+				wake = released
+			}
+
+			return ok
+		}
+
+		for input != nil || len(pending) > 0 {
+			select {
+			case item, ok := <-input:
+				if !ok {
+					input = nil
+					continue
+				}
+
+				// failed items are passed through without being limited
+				if item.Err != nil || tryAcquire(item) {
+					output <- item
+					continue
+				}
+
+				pending = append(pending, item)
+			case <-wake:
+				wake = nil
+				remaining := pending[:0]
+				for _, item := range pending {
+					if tryAcquire(item) {
+						output <- item
+					} else {
+						remaining = append(remaining, item)
+					}
+				}
+				pending = remaining
+			}
+		}
+	}()
+
+	return output, release
+}
@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	limiter := NewLimiter(2)
+
+	if !limiter.TryAcquire("a") || !limiter.TryAcquire("a") {
+		t.Fatal("unable to acquire a key under its limit")
+	}
+	if limiter.TryAcquire("a") {
+		t.Error("acquired a key over its limit")
+	}
+	if !limiter.TryAcquire("b") {
+		t.Error("a saturated key blocked another key")
+	}
+
+	limiter.Release("a")
+	if !limiter.TryAcquire("a") {
+		t.Error("unable to acquire a released key")
+	}
+}
+
+func TestWorkLimited(t *testing.T) {
+	var mu sync.Mutex
+	active := make(map[string]int)
+	peak := make(map[string]int)
+
+	input := Gen("a", "a", "a", "a", "b", "b")
+	output := Work(WorkOptions[string, string]{
+		Input:   input,
+		Workers: 4,
+		Name:    "limited",
+		Limiter: NewLimiter(1),
+		Key:     func(item string) string { return item },
+		Process: func(item string) (string, error) {
+			mu.Lock()
+			active[item]++
+			peak[item] = max(peak[item], active[item])
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			active[item]--
+			mu.Unlock()
+			return item, nil
+		},
+	})
+
+	count := 0
+	for range output {
+		count++
+	}
+
+	if count != 6 {
+		t.Errorf("processed %v items, want 6", count)
+	}
+	for key, n := range peak {
+		if n > 1 {
+			t.Errorf("%v items with key %v were processed at once, want at most 1", n, key)
+		}
+	}
+}
+
+func TestLimiterAcquire(t *testing.T) {
+	limiter := NewLimiter(1)
+	limiter.TryAcquire("a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Acquire(ctx, "a"); err == nil {
+		t.Error("acquired a saturated key")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		limiter.Release("a")
+	}()
+
+	if err := limiter.Acquire(context.Background(), "a"); err != nil {
+		t.Errorf("unable to acquire a released key: %v", err)
+	}
+}
+
+func TestWorkLimitedOutside(t *testing.T) {
+	limiter := NewLimiter(1)
+	limiter.TryAcquire("a")
+
+	output := Work(WorkOptions[string, string]{
+		Input:   Gen("a"),
+		Workers: 1,
+		Name:    "limited",
+		Limiter: limiter,
+		Key:     func(item string) string { return item },
+		Process: func(item string) (string, error) { return item, nil },
+	})
+
+	// keys released outside of the pipeline wake the items waiting on them
+	time.Sleep(10 * time.Millisecond)
+	limiter.Release("a")
+
+	select {
+	case <-output:
+	case <-time.After(time.Second):
+		t.Error("item wasn't processed after its key was released")
+	}
+}
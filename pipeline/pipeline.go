@@ -19,6 +19,9 @@ type WorkOptions[I any, O any] struct {
 	Process        func(I) (O, error)
 	Name           string
 	MetricsEnabled bool
+	// optional, caps how many items with the same Key are processed at once
+	Limiter *Limiter
+	Key     func(I) string
 }
 
 func Gen[I any](inputs ...I) <-chan Result[I] {
@@ -57,9 +60,12 @@ func Work[I any, O any](opts WorkOptions[I, O]) <-chan Result[O] {
 		logger.With("worker", worker)
 		start := time.Now()
 		if item.Err != nil {
+			// failed items are passed through when the step doesn't change their type,
+			// so the end of the pipeline can tell which item failed
+			passthrough, _ := any(item.Item).(*O)
 			output <- Result[O]{
 				Err:  item.Err,
-				Item: nil,
+				Item: passthrough,
 			}
 			return
 		}
@@ -78,11 +84,20 @@ func Work[I any, O any](opts WorkOptions[I, O]) <-chan Result[O] {
 			Item: &raw,
 		}
 	}
+	input := opts.Input
+	var release func(I)
+	if opts.Limiter != nil {
+		input, release = limit(opts)
+	}
+
 	worker := func(worker int) {
 		defer wg.Done()
 
-		for item := range opts.Input {
+		for item := range input {
 			process(worker, item)
+			if release != nil && item.Err == nil {
+				release(*item.Item)
+			}
 		}
 		log.Debug("worker exiting", "name", opts.Name, "worker", worker)
 	}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/parsers/basic"
+	"github.com/CelestialCrafter/crawler/scope"
+)
+
+// redirectPolicy returns which redirects are followed during a fetch.
+// targets of redirects that aren't followed are enqueued instead
+func redirectPolicy(sc *scope.Scope) (basic.FollowRedirect, error) {
+	policy := common.Options.RedirectPolicy
+	switch policy {
+	case "follow", "same_host", "enqueue":
+	default:
+		return nil, fmt.Errorf("unknown redirect policy: %v", policy)
+	}
+
+	return func(ctx context.Context, from *url.URL, to *url.URL) bool {
+		logger := common.LoggerFromContext(ctx).With("from", from, "to", to)
+
+		switch {
+		case policy == "enqueue":
+			return false
+		case policy == "same_host" && from.Host != to.Host:
+			logger.Debug("not following redirect to another host")
+			return false
+		}
+
+		// every hop is checked the same way as urls from the frontier
+		err := sc.CheckUrl(to)
+		if err != nil {
+			logger.Debug("not following redirect out of scope", "reason", err)
+			return false
+		}
+
+		err = crawlAllowed(to, ctx)
+		if err != nil {
+			logger.Debug("not following redirect", "reason", err)
+			return false
+		}
+
+		return true
+	}, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+// robotsResponse is a fetched robots.txt, status 0 meaning it was unreachable
+type robotsResponse struct {
+	status int
+	body   []byte
+}
+
+// robotsCache shares fetched robots.txt between crawler instances
+type robotsCache interface {
+	// Load returns the response stored for a host, and when it expires
+	Load(host string) (resp robotsResponse, expires time.Time, ok bool, err error)
+	// Lock claims fetching robots.txt for a host, returning false if another instance already has
+	Lock(host string) (bool, error)
+	// Store saves the response for a host, and releases its lock if this instance still holds it
+	Store(host string, resp robotsResponse, ttl time.Duration) error
+}
+
+// set when robots.txt is shared through valkey
+var sharedRobots robotsCache
+
+type valkeyRobotsCache struct {
+	vk valkey.Client
+}
+
+func newValkeyRobotsCache(vk valkey.Client) *valkeyRobotsCache {
+	return &valkeyRobotsCache{vk: vk}
+}
+
+func robotsKey(host string) string {
+	return "robots:" + host
+}
+
+func robotsLockKey(host string) string {
+	return "robots:lock:" + host
+}
+
+func (c *valkeyRobotsCache) Load(host string) (robotsResponse, time.Time, bool, error) {
+	vk := c.vk
+	resps := vk.DoMulti(
+		context.Background(),
+		vk.B().Get().Key(robotsKey(host)).Build(),
+		vk.B().Pttl().Key(robotsKey(host)).Build(),
+	)
+
+	value, err := resps[0].AsBytes()
+	if valkey.IsValkeyNil(err) {
+		return robotsResponse{}, time.Time{}, false, nil
+	}
+	if err != nil {
+		return robotsResponse{}, time.Time{}, false, err
+	}
+
+	ttl, err := resps[1].AsInt64()
+	if err != nil {
+		return robotsResponse{}, time.Time{}, false, err
+	}
+
+	// stored as the status, a newline, then the body
+	statusBytes, body, found := bytes.Cut(value, []byte("\n"))
+	if !found {
+		return robotsResponse{}, time.Time{}, false, errors.New("malformed robots.txt cache entry")
+	}
+
+	status, err := strconv.Atoi(string(statusBytes))
+	if err != nil {
+		return robotsResponse{}, time.Time{}, false, err
+	}
+
+	expires := time.Now().Add(time.Duration(ttl) * time.Millisecond)
+	return robotsResponse{status: status, body: body}, expires, true, nil
+}
+
+// deletes a lock if it is held by the given worker,
+// so a lock that expired and was taken by another instance isn't released
+var unlockScript = valkey.NewLuaScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func (c *valkeyRobotsCache) Lock(host string) (bool, error) {
+	vk := c.vk
+	err := vk.Do(
+		context.Background(),
+		vk.
+			B().
+			Set().
+			Key(robotsLockKey(host)).
+			Value(common.Options.WorkerId).
+			Nx().
+			Px(2*common.Options.CrawlTimeout).
+			Build(),
+	).Error()
+
+	if valkey.IsValkeyNil(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (c *valkeyRobotsCache) Store(host string, resp robotsResponse, ttl time.Duration) error {
+	value := append([]byte(strconv.Itoa(resp.status)+"\n"), resp.body...)
+
+	vk := c.vk
+	err := vk.Do(
+		context.Background(),
+		vk.B().Set().Key(robotsKey(host)).Value(valkey.BinaryString(value)).Px(ttl).Build(),
+	).Error()
+	if err != nil {
+		return err
+	}
+
+	return unlockScript.Exec(
+		context.Background(),
+		vk,
+		[]string{robotsLockKey(host)},
+		[]string{common.Options.WorkerId},
+	).Error()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/valkey-io/valkey-go"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+func newTestValkey(t *testing.T) (*miniredis.Miniredis, valkey.Client) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	vk, err := valkey.NewClient(valkey.ClientOption{
+		InitAddress:  []string{server.Addr()},
+		DisableCache: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(vk.Close)
+
+	return server, vk
+}
+
+func TestValkeyRobotsCache(t *testing.T) {
+	common.Options = common.Default
+	common.Options.WorkerId = "test"
+
+	server, vk := newTestValkey(t)
+	cache := newValkeyRobotsCache(vk)
+
+	locked, err := cache.Lock("a.com")
+	if err != nil || !locked {
+		t.Fatalf("Lock() = %v, %v, want the lock", locked, err)
+	}
+
+	locked, err = cache.Lock("a.com")
+	if err != nil || locked {
+		t.Fatalf("second Lock() = %v, %v, want it to be held already", locked, err)
+	}
+
+	err = cache.Store("a.com", robotsResponse{status: 200, body: []byte("User-agent: *")}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.Exists(robotsLockKey("a.com")) {
+		t.Error("lock wasn't released after storing")
+	}
+
+	resp, expires, ok, err := cache.Load("a.com")
+	if err != nil || !ok {
+		t.Fatalf("Load() = %v, %v, want the stored response", ok, err)
+	}
+	if resp.status != 200 || string(resp.body) != "User-agent: *" {
+		t.Errorf("Load() = %+v, want the stored response", resp)
+	}
+	if time.Until(expires) <= 0 {
+		t.Errorf("stored response expires at %v, want it in the future", expires)
+	}
+
+	// a lock that expired and was taken by another instance is kept
+	server.Set(robotsLockKey("b.com"), "other")
+	err = cache.Store("b.com", robotsResponse{status: 404}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner, _ := server.Get(robotsLockKey("b.com")); owner != "other" {
+		t.Errorf("lock owner = %q, want another instance's lock to be kept", owner)
+	}
+}
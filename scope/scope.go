@@ -0,0 +1,156 @@
+package scope
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/urlnorm"
+)
+
+// Scope decides which urls are allowed to enter the frontier
+type Scope struct {
+	allowedDomains []string
+	blockedDomains []string
+	include        []*regexp.Regexp
+	exclude        []*regexp.Regexp
+	schemes        []string
+	ports          []int
+	maxDepth       int
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compile scope pattern %q: %w", pattern, err)
+		}
+		compiled[i] = re
+	}
+
+	return compiled, nil
+}
+
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, len(domains))
+	for i, domain := range domains {
+		normalized[i] = strings.TrimSuffix(strings.ToLower(domain), ".")
+	}
+
+	return normalized
+}
+
+func New() (*Scope, error) {
+	include, err := compilePatterns(common.Options.IncludePatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude, err := compilePatterns(common.Options.ExcludePatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	schemes := make([]string, len(common.Options.AllowedSchemes))
+	for i, scheme := range common.Options.AllowedSchemes {
+		schemes[i] = strings.ToLower(scheme)
+	}
+
+	return &Scope{
+		allowedDomains: normalizeDomains(common.Options.AllowedDomains),
+		blockedDomains: normalizeDomains(common.Options.BlockedDomains),
+		include:        include,
+		exclude:        exclude,
+		schemes:        schemes,
+		ports:          common.Options.AllowedPorts,
+		maxDepth:       common.Options.MaxDepth,
+	}, nil
+}
+
+// matchDomain matches a host against "example.com", which only matches itself,
+// or "*.example.com", which matches every subdomain of example.com
+func matchDomain(host string, pattern string) bool {
+	suffix, wildcard := strings.CutPrefix(pattern, "*.")
+	if wildcard {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+
+	return host == pattern
+}
+
+func matchAnyDomain(host string, patterns []string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		return matchDomain(host, pattern)
+	})
+}
+
+func matchAnyPattern(u string, patterns []*regexp.Regexp) bool {
+	return slices.ContainsFunc(patterns, func(re *regexp.Regexp) bool {
+		return re.MatchString(u)
+	})
+}
+
+func port(u *url.URL) (int, error) {
+	p := u.Port()
+	if p == "" {
+		p = urlnorm.DefaultPorts[u.Scheme]
+	}
+	if p == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(p)
+}
+
+// Check returns why a url at depth is out of scope, or nil if it is in scope
+func (s *Scope) Check(u *url.URL, depth int) error {
+	if s.maxDepth > 0 && depth > s.maxDepth {
+		return fmt.Errorf("depth %d is over the max depth", depth)
+	}
+
+	return s.CheckUrl(u)
+}
+
+// CheckUrl is Check, without checking the url's depth
+func (s *Scope) CheckUrl(u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	if len(s.schemes) > 0 && !slices.Contains(s.schemes, scheme) {
+		return fmt.Errorf("scheme %v is not allowed", scheme)
+	}
+
+	if len(s.ports) > 0 {
+		p, err := port(u)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Contains(s.ports, p) {
+			return fmt.Errorf("port %d is not allowed", p)
+		}
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if matchAnyDomain(host, s.blockedDomains) {
+		return fmt.Errorf("domain %v is blocked", host)
+	}
+
+	if len(s.allowedDomains) > 0 && !matchAnyDomain(host, s.allowedDomains) {
+		return fmt.Errorf("domain %v is not allowed", host)
+	}
+
+	urlString := u.String()
+	if matchAnyPattern(urlString, s.exclude) {
+		return fmt.Errorf("url matched an exclude pattern")
+	}
+
+	if len(s.include) > 0 && !matchAnyPattern(urlString, s.include) {
+		return fmt.Errorf("url didn't match any include patterns")
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package scope
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+func newTestScope(t *testing.T) *Scope {
+	t.Helper()
+
+	common.Options = common.Default
+	common.Options.AllowedDomains = []string{"Example.com.", "*.example.com", "other.org"}
+	common.Options.BlockedDomains = []string{"private.example.com"}
+	common.Options.ExcludePatterns = []string{`/drafts/`}
+	common.Options.AllowedSchemes = []string{"HTTP", "https"}
+	common.Options.AllowedPorts = []int{80, 443, 8080}
+	common.Options.MaxDepth = 2
+
+	sc, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sc
+}
+
+func TestCheck(t *testing.T) {
+	sc := newTestScope(t)
+
+	tests := []struct {
+		url     string
+		depth   int
+		inScope bool
+	}{
+		{"http://example.com/", 0, true},
+		{"https://www.example.com/docs/", 2, true},
+		{"http://EXAMPLE.COM./blog", 1, true},
+		{"http://example.com:8080/", 0, true},
+		{"http://example.com/", 3, false},
+		// wildcards only match subdomains
+		{"http://notexample.com/", 0, false},
+		{"http://a.other.org/", 0, false},
+		{"http://private.example.com/", 0, false},
+		{"ftp://example.com/", 0, false},
+		{"http://example.com:8443/", 0, false},
+		// schemes without a default port only pass a port allowlist with an explicit port
+		{"ws://example.com/", 0, false},
+		{"http://example.com/docs/drafts/", 0, false},
+	}
+
+	for _, test := range tests {
+		u, err := url.Parse(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = sc.Check(u, test.depth)
+		if (err == nil) != test.inScope {
+			t.Errorf("Check(%q, %v) = %v, want in scope %v", test.url, test.depth, err, test.inScope)
+		}
+	}
+}
+
+func TestIncludePatterns(t *testing.T) {
+	common.Options = common.Default
+	common.Options.ExcludePatterns = nil
+	common.Options.IncludePatterns = []string{`^https://a\.com/docs/`}
+
+	sc, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for u, inScope := range map[string]bool{
+		"https://a.com/docs/page": true,
+		"https://a.com/blog/page": false,
+	} {
+		parsed, _ := url.Parse(u)
+		if err := sc.CheckUrl(parsed); (err == nil) != inScope {
+			t.Errorf("CheckUrl(%q) = %v, want in scope %v", u, err, inScope)
+		}
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	common.Options = common.Default
+	common.Options.ExcludePatterns = []string{"("}
+
+	_, err := New()
+	if err == nil {
+		t.Error("New() with an invalid pattern succeeded, want an error")
+	}
+}
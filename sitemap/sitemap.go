@@ -0,0 +1,184 @@
+package sitemap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+// https://www.sitemaps.org/protocol.html#index
+const MAX_BYTES = 50 * 1024 * 1024
+const MAX_URLS = 50000
+
+// https://www.sitemaps.org/protocol.html#prioritydef
+const DEFAULT_PRIORITY = 0.5
+
+// https://www.w3.org/TR/NOTE-datetime
+var lastModLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+type Url struct {
+	Loc string
+	// zero if the sitemap didn't include it
+	LastMod  time.Time
+	Priority float64
+}
+
+type Sitemap struct {
+	Urls []Url
+	// sitemaps listed by a sitemap index
+	Sitemaps []string
+}
+
+type xmlSitemap struct {
+	Urls []struct {
+		Loc      string `xml:"loc"`
+		LastMod  string `xml:"lastmod"`
+		Priority string `xml:"priority"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+func parseLastMod(text string) time.Time {
+	text = strings.TrimSpace(text)
+	for _, layout := range lastModLayouts {
+		t, err := time.Parse(layout, text)
+		if err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// Parse reads a sitemap or sitemap index, which may be gzipped
+func Parse(r io.Reader) (Sitemap, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+
+	var reader io.Reader = br
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return Sitemap{}, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var raw xmlSitemap
+	err := xml.NewDecoder(io.LimitReader(reader, MAX_BYTES)).Decode(&raw)
+	if err != nil {
+		return Sitemap{}, err
+	}
+
+	sitemap := Sitemap{
+		Urls:     make([]Url, 0, min(len(raw.Urls), MAX_URLS)),
+		Sitemaps: make([]string, 0, len(raw.Sitemaps)),
+	}
+
+	for _, u := range raw.Urls {
+		if len(sitemap.Urls) >= MAX_URLS {
+			break
+		}
+
+		loc := strings.TrimSpace(u.Loc)
+		if loc == "" {
+			continue
+		}
+
+		priority, err := strconv.ParseFloat(strings.TrimSpace(u.Priority), 64)
+		if err != nil || priority < 0 || priority > 1 {
+			priority = DEFAULT_PRIORITY
+		}
+
+		sitemap.Urls = append(sitemap.Urls, Url{
+			Loc:      loc,
+			LastMod:  parseLastMod(u.LastMod),
+			Priority: priority,
+		})
+	}
+
+	for _, s := range raw.Sitemaps {
+		loc := strings.TrimSpace(s.Loc)
+		if loc != "" {
+			sitemap.Sitemaps = append(sitemap.Sitemaps, loc)
+		}
+	}
+
+	return sitemap, nil
+}
+
+func Fetch(ctx context.Context, client *http.Client, sitemapUrl string) (Sitemap, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", sitemapUrl, nil)
+	if err != nil {
+		return Sitemap{}, err
+	}
+
+	req.Header.Add("User-Agent", common.Options.UserAgent)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return Sitemap{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return Sitemap{}, fmt.Errorf("received status code: %d", res.StatusCode)
+	}
+
+	return Parse(res.Body)
+}
+
+// FetchFunc fetches and parses a single sitemap
+type FetchFunc func(ctx context.Context, sitemapUrl string) (Sitemap, error)
+
+// Crawl fetches sitemaps with fetch, and the sitemaps listed by any sitemap indexes,
+// up to maxSitemaps sitemaps in total
+func Crawl(ctx context.Context, fetch FetchFunc, sitemapUrls []string, maxSitemaps int) ([]Url, error) {
+	queue := append([]string{}, sitemapUrls...)
+	seen := make(map[string]struct{})
+	urls := make([]Url, 0)
+
+	var lastErr error
+	for len(queue) > 0 && len(seen) < maxSitemaps {
+		sitemapUrl := queue[0]
+		queue = queue[1:]
+
+		if _, ok := seen[sitemapUrl]; ok {
+			continue
+		}
+		seen[sitemapUrl] = struct{}{}
+
+		sitemap, err := fetch(ctx, sitemapUrl)
+		if err != nil {
+			lastErr = fmt.Errorf("unable to fetch sitemap %v: %w", sitemapUrl, err)
+			continue
+		}
+
+		urls = append(urls, sitemap.Urls...)
+		queue = append(queue, sitemap.Sitemaps...)
+	}
+
+	if len(urls) < 1 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return urls, nil
+}
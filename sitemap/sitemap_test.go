@@ -0,0 +1,126 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const urlset = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url>
+		<loc> http://a.com/ </loc>
+		<lastmod>2024-05-01</lastmod>
+		<priority>0.8</priority>
+	</url>
+	<url>
+		<loc>http://a.com/other</loc>
+		<lastmod>2024-05-01T10:30+02:00</lastmod>
+		<priority>2</priority>
+	</url>
+	<url>
+		<loc></loc>
+	</url>
+</urlset>`
+
+func TestParse(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte(urlset))
+	gz.Close()
+
+	for name, body := range map[string][]byte{"plain": []byte(urlset), "gzipped": gzipped.Bytes()} {
+		sitemap, err := Parse(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+
+		// urls without a loc are skipped
+		if len(sitemap.Urls) != 2 {
+			t.Fatalf("%v: parsed %v urls, want 2", name, len(sitemap.Urls))
+		}
+
+		first, other := sitemap.Urls[0], sitemap.Urls[1]
+		if first.Loc != "http://a.com/" || first.Priority != 0.8 || !first.LastMod.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("%v: first url = %+v", name, first)
+		}
+
+		// out of range priorities fall back to the default
+		if other.Priority != DEFAULT_PRIORITY {
+			t.Errorf("%v: priority = %v, want %v", name, other.Priority, DEFAULT_PRIORITY)
+		}
+		if !other.LastMod.Equal(time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)) {
+			t.Errorf("%v: lastmod = %v, want 2024-05-01 08:30 UTC", name, other.LastMod)
+		}
+	}
+}
+
+func TestParseIndex(t *testing.T) {
+	index := `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+		<sitemap><loc>http://a.com/1.xml</loc></sitemap>
+		<sitemap><loc>http://a.com/2.xml</loc></sitemap>
+	</sitemapindex>`
+
+	sitemap, err := Parse(strings.NewReader(index))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sitemap.Urls) != 0 || len(sitemap.Sitemaps) != 2 || sitemap.Sitemaps[1] != "http://a.com/2.xml" {
+		t.Errorf("Parse() = %+v, want the two listed sitemaps", sitemap)
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	sitemaps := map[string]Sitemap{
+		"index": {Sitemaps: []string{"a", "b", "index"}},
+		"a":     {Urls: []Url{{Loc: "http://a.com/a"}}, Sitemaps: []string{"c"}},
+		"b":     {Urls: []Url{{Loc: "http://a.com/b"}}},
+		"c":     {Urls: []Url{{Loc: "http://a.com/c"}}},
+	}
+
+	fetched := make([]string, 0)
+	fetch := func(ctx context.Context, sitemapUrl string) (Sitemap, error) {
+		fetched = append(fetched, sitemapUrl)
+		sitemap, ok := sitemaps[sitemapUrl]
+		if !ok {
+			return Sitemap{}, errors.New("not found")
+		}
+
+		return sitemap, nil
+	}
+
+	// indexes are followed, and sitemaps listed twice are only fetched once
+	urls, err := Crawl(context.Background(), fetch, []string{"index"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 3 || len(fetched) != 4 {
+		t.Errorf("crawled %v urls from %v, want 3 urls from 4 sitemaps", len(urls), fetched)
+	}
+
+	// at most maxSitemaps are fetched
+	fetched = fetched[:0]
+	urls, err = Crawl(context.Background(), fetch, []string{"index"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 1 || len(fetched) != 2 {
+		t.Errorf("crawled %v urls from %v, want 1 url from 2 sitemaps", len(urls), fetched)
+	}
+
+	// failing sitemaps are only an error if nothing was found
+	_, err = Crawl(context.Background(), fetch, []string{"missing"}, 10)
+	if err == nil {
+		t.Error("crawling only missing sitemaps succeeded, want an error")
+	}
+
+	urls, err = Crawl(context.Background(), fetch, []string{"missing", "b"}, 10)
+	if err != nil || len(urls) != 1 {
+		t.Errorf("Crawl() = %v, %v, want the url from the sitemap that was found", urls, err)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/puzpuzpuz/xsync/v3"
+
+	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/frontier"
+	"github.com/CelestialCrafter/crawler/parsers/basic"
+	"github.com/CelestialCrafter/crawler/pipeline"
+	"github.com/CelestialCrafter/crawler/sitemap"
+)
+
+// max amount of sitemaps fetched per host, including ones listed by sitemap indexes
+const MAX_HOST_SITEMAPS = 10
+
+// max amount of hosts whose sitemaps are discovered at once, which bounds the memory used to decode them
+const MAX_CONCURRENT_DISCOVERIES = 4
+
+// max amount of sitemaps fetched when seeding from initial_sitemaps
+const MAX_INITIAL_SITEMAPS = 1000
+
+// hosts which sitemaps have already been discovered for by this process
+var sitemapHosts = xsync.NewMapOf[string, struct{}]()
+
+// amount of hosts whose sitemaps are being discovered, which may still enqueue urls
+var pendingSitemaps atomic.Int64
+
+func sitemapEntries(urls []sitemap.Url, depth int) []frontier.Entry {
+	entries := make([]frontier.Entry, len(urls))
+	for i, u := range urls {
+		entries[i] = frontier.Entry{
+			Url:   u.Loc,
+			Depth: depth,
+			Hints: &frontier.Hints{
+				Priority: u.Priority,
+				LastMod:  u.LastMod,
+			},
+		}
+	}
+
+	return entries
+}
+
+// hostSitemaps returns the sitemaps listed in robots.txt, or /sitemap.xml if there are none
+func hostSitemaps(u *url.URL) []string {
+	entry, ok := robotsMap.Load(u.Host)
+	if ok && len(entry.sitemaps) > 0 {
+		return entry.sitemaps
+	}
+
+	return []string{fmt.Sprint(u.Scheme, "://", u.Host, "/sitemap.xml")}
+}
+
+// sitemapDiscovery fetches the sitemaps of hosts the same way pages are fetched,
+// respecting robots.txt, crawl delays, connection limits, and the redirect policy
+type sitemapDiscovery struct {
+	client *http.Client
+	// shared with the fetch stage, so sitemaps count towards a host's connections
+	limiter *pipeline.Limiter
+	// bounds how many hosts' sitemaps are fetched and decoded at once
+	slots   chan struct{}
+	enqueue func([]frontier.Entry)
+}
+
+func newSitemapDiscovery(follow basic.FollowRedirect, limiter *pipeline.Limiter, enqueue func([]frontier.Entry)) *sitemapDiscovery {
+	return &sitemapDiscovery{
+		client:  &http.Client{CheckRedirect: basic.CheckRedirect(follow)},
+		limiter: limiter,
+		slots:   make(chan struct{}, MAX_CONCURRENT_DISCOVERIES),
+		enqueue: enqueue,
+	}
+}
+
+// fetch fetches a sitemap once robots.txt allows it, its host is due, and a connection to it is free.
+// it runs outside of the pipeline, so waiting on the host doesn't hold up any workers
+func (d *sitemapDiscovery) fetch(ctx context.Context, sitemapUrl string) (sitemap.Sitemap, error) {
+	u, err := url.Parse(sitemapUrl)
+	if err != nil {
+		return sitemap.Sitemap{}, err
+	}
+
+	// sitemaps may be listed on other hosts, which have their own robots.txt
+	allowedCtx, cancel := context.WithTimeout(ctx, common.Options.CrawlTimeout)
+	err = crawlAllowed(u, allowedCtx)
+	connection := resolveConnection(u, allowedCtx)
+	cancel()
+	if err != nil {
+		return sitemap.Sitemap{}, err
+	}
+
+	err = waitCrawlable(u, ctx)
+	if err != nil {
+		return sitemap.Sitemap{}, err
+	}
+
+	err = d.limiter.Acquire(ctx, connection)
+	if err != nil {
+		return sitemap.Sitemap{}, err
+	}
+	defer d.limiter.Release(connection)
+
+	ctx, cancel = context.WithTimeout(ctx, common.Options.CrawlTimeout)
+	defer cancel()
+
+	return sitemap.Fetch(ctx, d.client, sitemapUrl)
+}
+
+// discover crawls the sitemaps of a host the first time it is seen,
+// and passes the urls in them to enqueue
+func (d *sitemapDiscovery) discover(data *crawlDataContext) {
+	if !common.Options.DiscoverSitemaps {
+		return
+	}
+
+	_, loaded := sitemapHosts.LoadOrStore(data.url.Host, struct{}{})
+	if loaded {
+		return
+	}
+
+	logger := common.LoggerFromContext(data.ctx)
+	sitemapUrls := hostSitemaps(data.url)
+	depth := data.depth + 1
+
+	pendingSitemaps.Add(1)
+	go func() {
+		defer pendingSitemaps.Add(-1)
+
+		d.slots <- struct{}{}
+		defer func() { <-d.slots }()
+
+		urls, err := sitemap.Crawl(data.ctx, d.fetch, sitemapUrls, MAX_HOST_SITEMAPS)
+		if err != nil {
+			logger.Debug("unable to discover sitemaps", "error", err)
+			return
+		}
+
+		logger.Debug("discovered sitemap urls", "count", len(urls))
+		if len(urls) > 0 {
+			d.enqueue(sitemapEntries(urls, depth))
+		}
+	}()
+}
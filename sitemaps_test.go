@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/frontier"
+	"github.com/CelestialCrafter/crawler/pipeline"
+)
+
+// sitemapSite serves robots.txt and a sitemap index listing two sitemaps, recording when each sitemap was fetched
+type sitemapSite struct {
+	*httptest.Server
+	mu      sync.Mutex
+	fetched map[string]time.Time
+}
+
+func newSitemapSite(robots string) *sitemapSite {
+	site := &sitemapSite{fetched: make(map[string]time.Time)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, robots, site.URL)
+	})
+	mux.HandleFunc("/sitemaps/", func(w http.ResponseWriter, r *http.Request) {
+		site.mu.Lock()
+		site.fetched[r.URL.Path] = time.Now()
+		site.mu.Unlock()
+
+		if r.URL.Path == "/sitemaps/index.xml" {
+			fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%[1]v/sitemaps/1.xml</loc></sitemap><sitemap><loc>%[1]v/sitemaps/2.xml</loc></sitemap></sitemapindex>`, site.URL)
+			return
+		}
+
+		fmt.Fprintf(w, `<urlset><url><loc>%v%v.html</loc></url></urlset>`, site.URL, r.URL.Path)
+	})
+	site.Server = httptest.NewServer(mux)
+
+	return site
+}
+
+// discoverHost discovers the sitemaps of server's host, and waits for them to be enqueued
+func discoverHost(t *testing.T, d *sitemapDiscovery, server *httptest.Server) {
+	t.Helper()
+
+	data, err := newCrawlDataContext(frontier.Entry{Url: server.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// discovery starts after robots.txt was checked
+	err = crawlAllowed(data.url, data.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.discover(data)
+	for pendingSitemaps.Load() > 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSitemapDiscovery(t *testing.T) {
+	common.Options = common.Default
+	common.Options.DiscoverSitemaps = true
+	common.Options.DefaultCrawlDelay = 100 * time.Millisecond
+
+	site := newSitemapSite("User-agent: *\nAllow: /\nSitemap: %v/sitemaps/index.xml\n")
+	defer site.Close()
+
+	var entries []frontier.Entry
+	d := newSitemapDiscovery(nil, pipeline.NewLimiter(1), func(e []frontier.Entry) {
+		entries = append(entries, e...)
+	})
+	discoverHost(t, d, site.Server)
+
+	urls := make([]string, len(entries))
+	for i, entry := range entries {
+		urls[i] = entry.Url
+	}
+	slices.Sort(urls)
+
+	want := []string{site.URL + "/sitemaps/1.xml.html", site.URL + "/sitemaps/2.xml.html"}
+	if !slices.Equal(urls, want) {
+		t.Errorf("enqueued %v, want %v", urls, want)
+	}
+
+	// sitemaps wait for the host's crawl delay like pages do
+	times := make([]time.Time, 0, len(site.fetched))
+	for _, fetched := range site.fetched {
+		times = append(times, fetched)
+	}
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < 90*time.Millisecond {
+			t.Errorf("sitemaps were fetched %v apart, want at least the crawl delay", gap)
+		}
+	}
+}
+
+func TestSitemapDiscoveryRobots(t *testing.T) {
+	common.Options = common.Default
+	common.Options.DiscoverSitemaps = true
+	common.Options.DefaultCrawlDelay = time.Millisecond
+
+	site := newSitemapSite("User-agent: *\nDisallow: /sitemaps/\nSitemap: %v/sitemaps/index.xml\n")
+	defer site.Close()
+
+	var entries []frontier.Entry
+	d := newSitemapDiscovery(nil, pipeline.NewLimiter(1), func(e []frontier.Entry) {
+		entries = append(entries, e...)
+	})
+	discoverHost(t, d, site.Server)
+
+	if len(site.fetched) != 0 || len(entries) != 0 {
+		t.Errorf("fetched %v and enqueued %v, want sitemaps disallowed by robots.txt to be skipped", site.fetched, entries)
+	}
+}
+
+func TestSitemapDiscoveryLimiter(t *testing.T) {
+	common.Options = common.Default
+	common.Options.DiscoverSitemaps = true
+	common.Options.DefaultCrawlDelay = time.Millisecond
+
+	site := newSitemapSite("User-agent: *\nSitemap: %v/sitemaps/1.xml\n")
+	defer site.Close()
+
+	limiter := pipeline.NewLimiter(1)
+	d := newSitemapDiscovery(nil, limiter, func([]frontier.Entry) {})
+
+	// the host's only connection is taken by a page being fetched
+	host := site.Listener.Addr().String()
+	limiter.TryAcquire(host)
+	released := time.Now().Add(100 * time.Millisecond)
+	time.AfterFunc(100*time.Millisecond, func() { limiter.Release(host) })
+
+	discoverHost(t, d, site.Server)
+
+	fetched, ok := site.fetched["/sitemaps/1.xml"]
+	if !ok || fetched.Before(released) {
+		t.Errorf("sitemap was fetched at %v, want it to wait for the connection to be released at %v", fetched, released)
+	}
+}
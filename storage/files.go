@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+// https://man7.org/linux/man-pages/man3/pathconf.3.html
+const MAX_FILENAME_LENGTH = 255
+
+// Files writes each document to its own protobuf file, under a directory for its host
+type Files struct {
+	dir string
+}
+
+func NewFiles() (*Files, error) {
+	dir := path.Join(common.Options.DataPath, "crawled")
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Files{dir: dir}, nil
+}
+
+// filename returns the name of a document's file, which is its hash if the encoded path is too long
+func filename(crawl Crawl) string {
+	// the query is kept, so pages that only differ by it don't overwrite each other
+	name := strings.TrimPrefix(crawl.Location.Path, "/")
+	if crawl.Location.RawQuery != "" {
+		name += "?" + crawl.Location.RawQuery
+	}
+
+	encoded := base64.URLEncoding.EncodeToString([]byte(name)) + ".pb"
+	if len(encoded) <= MAX_FILENAME_LENGTH {
+		return encoded
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:]) + ".pb"
+}
+
+func (f *Files) Write(crawl Crawl) error {
+	// the last crawl's file is kept
+	if crawl.Unchanged {
+		return nil
+	}
+
+	output, err := proto.Marshal(reference(crawl.Document))
+	if err != nil {
+		return err
+	}
+
+	hostPath := path.Join(f.dir, crawl.Location.Host)
+	err = os.MkdirAll(hostPath, 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path.Join(hostPath, filename(crawl)), output, 0644)
+}
+
+func (f *Files) Close() error {
+	return nil
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+// delay before retrying a failed upload, which doubles with each attempt
+const UPLOAD_BASE_DELAY = time.Second
+
+// S3 uploads objects to an S3-compatible bucket
+type S3 struct {
+	client *minio.Client
+	bucket string
+	logger *log.Logger
+
+	// rotated files still being uploaded
+	pending sync.WaitGroup
+	mu      sync.Mutex
+	err     error
+}
+
+func NewS3() (*S3, error) {
+	client, err := minio.New(common.Options.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(common.Options.S3AccessKey, common.Options.S3SecretKey, ""),
+		Secure: common.Options.S3UseSSL,
+		Region: common.Options.S3Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	bucket := common.Options.S3Bucket
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: common.Options.S3Region})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &S3{
+		client: client,
+		bucket: bucket,
+		logger: log.WithPrefix("storage/s3"),
+	}, nil
+}
+
+// key returns the object key of name, laid out by the s3_key_layout option
+func key(name string) string {
+	return strings.NewReplacer(
+		"{output}", common.Options.Output,
+		"{worker}", common.Options.WorkerId,
+		"{date}", time.Now().UTC().Format("2006/01/02"),
+		"{name}", name,
+	).Replace(common.Options.S3KeyLayout)
+}
+
+// put uploads the object returned by open, retrying failed uploads.
+// objects larger than the part size are uploaded in parts
+func (s *S3) put(key string, contentType string, open func() (io.Reader, int64, error)) error {
+	var err error
+	delay := UPLOAD_BASE_DELAY
+	for attempt := 1; attempt <= common.Options.S3MaxAttempts; attempt++ {
+		var reader io.Reader
+		var size int64
+		reader, size, err = open()
+		if err != nil {
+			return err
+		}
+
+		_, err = s.client.PutObject(context.Background(), s.bucket, key, reader, size, minio.PutObjectOptions{
+			ContentType: contentType,
+			PartSize:    common.Options.S3PartSize,
+		})
+		if closer, ok := reader.(io.Closer); ok {
+			closer.Close()
+		}
+		if err == nil {
+			return nil
+		}
+
+		s.logger.Warn("unable to upload object", "key", key, "attempt", attempt, "error", err)
+		if attempt < common.Options.S3MaxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+
+	return err
+}
+
+// uploadFiles uploads rotated files in the background, and removes them once they're uploaded
+func (s *S3) uploadFiles(files ...string) {
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+
+		for _, file := range files {
+			err := s.put(key(path.Base(file)), "application/octet-stream", func() (io.Reader, int64, error) {
+				f, err := os.Open(file)
+				if err != nil {
+					return nil, 0, err
+				}
+
+				info, err := f.Stat()
+				if err != nil {
+					f.Close()
+					return nil, 0, err
+				}
+
+				return f, info.Size(), nil
+			})
+			if err == nil {
+				err = os.Remove(file)
+			}
+
+			if err != nil {
+				s.logger.Error("unable to upload file", "file", file, "error", err)
+				s.mu.Lock()
+				s.err = fmt.Errorf("unable to upload %v: %w", file, err)
+				s.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// wait blocks until every rotated file is uploaded, and returns the last upload error
+func (s *S3) wait() error {
+	s.pending.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// S3Files uploads each document as its own protobuf object, under a prefix for its host
+type S3Files struct {
+	s3 *S3
+}
+
+func (f *S3Files) Write(crawl Crawl) error {
+	if crawl.Unchanged {
+		return nil
+	}
+
+	output, err := proto.Marshal(reference(crawl.Document))
+	if err != nil {
+		return err
+	}
+
+	name := path.Join(crawl.Location.Host, filename(crawl))
+	return f.s3.put(key(name), "application/protobuf", func() (io.Reader, int64, error) {
+		return bytes.NewReader(output), int64(len(output)), nil
+	})
+}
+
+func (f *S3Files) Close() error {
+	return nil
+}
+
+// uploaded is a writer whose rotated files are uploaded
+type uploaded struct {
+	Writer
+	s3 *S3
+}
+
+// Close closes the writer, which rotates its last file, then waits for every upload
+func (u uploaded) Close() error {
+	err := u.Writer.Close()
+	if err != nil {
+		return err
+	}
+
+	return u.s3.wait()
+}
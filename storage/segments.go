@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/CelestialCrafter/crawler/common"
+	pb "github.com/CelestialCrafter/crawler/protos"
+)
+
+const (
+	SEGMENT_EXTENSION = ".seg"
+	INDEX_EXTENSION   = ".idx"
+	// each record starts with the length of its compressed document
+	RECORD_PREFIX_SIZE = 4
+	// each index entry is a url hash, record offset, and record length
+	INDEX_ENTRY_SIZE = 8 + 8 + 4
+)
+
+var ErrNotFound = errors.New("document not found")
+
+type indexEntry struct {
+	hash   uint64
+	offset int64
+	length uint32
+}
+
+// Segments appends zstd compressed documents to segment files,
+// starting a new segment once the current one reaches a max size.
+// each segment's index is sorted by url hash and written when the segment is closed
+type Segments struct {
+	mu      sync.Mutex
+	dir     string
+	maxSize int64
+	encoder *zstd.Encoder
+	// called with a segment and its index once they're written, if it isn't nil
+	rotated func(files ...string)
+
+	file   *os.File
+	name   string
+	size   int64
+	serial int
+	index  []indexEntry
+}
+
+func NewSegments(rotated func(files ...string)) (*Segments, error) {
+	dir := path.Join(common.Options.DataPath, "segments")
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Segments{
+		dir:     dir,
+		maxSize: common.Options.SegmentMaxSize,
+		encoder: encoder,
+		rotated: rotated,
+	}, nil
+}
+
+func urlHash(u string) uint64 {
+	sum := sha256.Sum256([]byte(u))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+func (s *Segments) Write(crawl Crawl) error {
+	// the last crawl's record is kept
+	if crawl.Unchanged {
+		return nil
+	}
+
+	output, err := proto.Marshal(reference(crawl.Document))
+	if err != nil {
+		return err
+	}
+
+	record := s.encoder.EncodeAll(output, make([]byte, RECORD_PREFIX_SIZE))
+	length := uint32(len(record) - RECORD_PREFIX_SIZE)
+	binary.BigEndian.PutUint32(record, length)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file != nil && s.size >= s.maxSize {
+		err := s.rotate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.file == nil {
+		err := s.open()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = s.file.Write(record)
+	if err != nil {
+		return err
+	}
+
+	s.index = append(s.index, indexEntry{
+		hash:   urlHash(crawl.Document.Url),
+		offset: s.size,
+		length: length,
+	})
+	s.size += int64(len(record))
+
+	return nil
+}
+
+func (s *Segments) open() error {
+	s.serial++
+	// the worker id keeps workers sharing a directory or bucket from picking the same names
+	s.name = fmt.Sprintf("segment-%v-%05d-%v", time.Now().UTC().Format("20060102150405"), s.serial, fileWorkerId())
+
+	file, err := os.OpenFile(path.Join(s.dir, s.name+SEGMENT_EXTENSION), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	s.file = file
+	s.size = 0
+	s.index = nil
+
+	return nil
+}
+
+// rotate closes the current segment and writes its index
+func (s *Segments) rotate() error {
+	err := s.file.Close()
+	s.file = nil
+	if err != nil {
+		return err
+	}
+
+	// entries with the same hash stay in the order they were written, so the latest is last
+	slices.SortStableFunc(s.index, func(a indexEntry, b indexEntry) int {
+		return cmp.Compare(a.hash, b.hash)
+	})
+
+	index := make([]byte, 0, len(s.index)*INDEX_ENTRY_SIZE)
+	for _, entry := range s.index {
+		index = binary.BigEndian.AppendUint64(index, entry.hash)
+		index = binary.BigEndian.AppendUint64(index, uint64(entry.offset))
+		index = binary.BigEndian.AppendUint32(index, entry.length)
+	}
+
+	indexPath := path.Join(s.dir, s.name+INDEX_EXTENSION)
+	err = os.WriteFile(indexPath, index, 0644)
+	if err != nil {
+		return err
+	}
+
+	if s.rotated != nil {
+		s.rotated(path.Join(s.dir, s.name+SEGMENT_EXTENSION), indexPath)
+	}
+
+	return nil
+}
+
+func (s *Segments) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.encoder.Close()
+	if s.file == nil {
+		return nil
+	}
+
+	return s.rotate()
+}
+
+// readRecord reads the document stored at offset in a segment
+func readRecord(segment string, offset int64, length uint32) (*pb.Document, error) {
+	file, err := os.Open(segment)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// a record cut short by a crash fails instead of being decoded partially
+	compressed := make([]byte, length)
+	_, err = io.ReadFull(io.NewSectionReader(file, offset+RECORD_PREFIX_SIZE, int64(length)), compressed)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+
+	output, err := decoder.DecodeAll(compressed, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	document := new(pb.Document)
+	return document, proto.Unmarshal(output, document)
+}
+
+// Lookup returns the latest stored document for u from the segments in dir.
+// segments that are still being written aren't indexed yet
+func Lookup(dir string, u string) (*pb.Document, error) {
+	indexes, err := filepath.Glob(path.Join(dir, "*"+INDEX_EXTENSION))
+	if err != nil {
+		return nil, err
+	}
+
+	// names start with when the segment was created, so the newest segment is searched first
+	slices.Sort(indexes)
+	slices.Reverse(indexes)
+
+	hash := urlHash(u)
+	for _, indexPath := range indexes {
+		index, err := os.ReadFile(indexPath)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := func(i int) indexEntry {
+			b := index[i*INDEX_ENTRY_SIZE:]
+			return indexEntry{
+				hash:   binary.BigEndian.Uint64(b),
+				offset: int64(binary.BigEndian.Uint64(b[8:])),
+				length: binary.BigEndian.Uint32(b[16:]),
+			}
+		}
+
+		n := len(index) / INDEX_ENTRY_SIZE
+		end := sort.Search(n, func(i int) bool {
+			return entry(i).hash > hash
+		})
+
+		// the latest entry for the hash is checked first, and different urls with the same hash are skipped
+		segment := strings.TrimSuffix(indexPath, INDEX_EXTENSION) + SEGMENT_EXTENSION
+		for i := end - 1; i >= 0 && entry(i).hash == hash; i-- {
+			document, err := readRecord(segment, entry(i).offset, entry(i).length)
+			if err != nil {
+				return nil, err
+			}
+
+			if document.Url == u {
+				return document, nil
+			}
+		}
+	}
+
+	return nil, ErrNotFound
+}
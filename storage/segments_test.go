@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/CelestialCrafter/crawler/common"
+	pb "github.com/CelestialCrafter/crawler/protos"
+)
+
+func newTestSegments(t *testing.T) *Segments {
+	t.Helper()
+
+	common.Options = common.Default
+	common.Options.DataPath = t.TempDir()
+	common.Options.WorkerId = "host/1"
+	// every record starts a new segment
+	common.Options.SegmentMaxSize = 1
+
+	segments, err := NewSegments(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return segments
+}
+
+func writeDocument(t *testing.T, segments *Segments, u string, text string) {
+	t.Helper()
+
+	location, err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = segments.Write(Crawl{
+		Document: &pb.Document{Url: u, Text: []byte(text)},
+		Location: location,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSegmentsLookup(t *testing.T) {
+	segments := newTestSegments(t)
+	writeDocument(t, segments, "http://a.com/", "first")
+	writeDocument(t, segments, "http://a.com/?id=2", "other")
+	writeDocument(t, segments, "http://a.com/", "second")
+
+	err := segments.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := path.Join(common.Options.DataPath, "segments")
+	files, err := filepath.Glob(path.Join(dir, "*"+SEGMENT_EXTENSION))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("wrote %v segments, want 3", len(files))
+	}
+
+	// the latest crawl of a url is returned
+	document, err := Lookup(dir, "http://a.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(document.Text) != "second" {
+		t.Errorf("Lookup() = %q, want the latest crawl", document.Text)
+	}
+
+	// urls that only differ by their query are kept apart
+	document, err = Lookup(dir, "http://a.com/?id=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(document.Text) != "other" {
+		t.Errorf("Lookup() = %q, want the page with the query", document.Text)
+	}
+
+	_, err = Lookup(dir, "http://b.com/")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup() of a missing url returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestSegmentsTruncatedRecord(t *testing.T) {
+	segments := newTestSegments(t)
+	writeDocument(t, segments, "http://a.com/", "text")
+
+	err := segments.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := path.Join(common.Options.DataPath, "segments")
+	files, err := filepath.Glob(path.Join(dir, "*"+SEGMENT_EXTENSION))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("found segments %v, %v, want one segment", files, err)
+	}
+
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Truncate(files[0], info.Size()-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Lookup(dir, "http://a.com/")
+	if err == nil {
+		t.Error("Lookup() of a truncated record succeeded, want an error")
+	}
+}
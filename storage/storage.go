@@ -0,0 +1,90 @@
+// Package storage writes crawled documents to where they're kept
+package storage
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/CelestialCrafter/crawler/common"
+	pb "github.com/CelestialCrafter/crawler/protos"
+)
+
+// Crawl is a crawled document, with how it was crawled
+type Crawl struct {
+	Document *pb.Document
+	// where the document was fetched from, after following redirects
+	Location *url.URL
+	Depth    int
+	// wether the page was unchanged since its last crawl, so it wasn't downloaded again
+	Unchanged bool
+}
+
+// reference returns the document stored for a crawl,
+// which leaves out the content of duplicates since their first copy already has it
+func reference(document *pb.Document) *pb.Document {
+	if document.DuplicateOf == nil {
+		return document
+	}
+
+	return &pb.Document{
+		Url:         document.Url,
+		Children:    document.Children,
+		Metadata:    document.Metadata,
+		RedirectTo:  document.RedirectTo,
+		Truncated:   document.Truncated,
+		ContentHash: document.ContentHash,
+		DuplicateOf: document.DuplicateOf,
+	}
+}
+
+// fileWorkerId is the worker id escaped for use in file names, which it can't add directories to
+func fileWorkerId() string {
+	return url.PathEscape(common.Options.WorkerId)
+}
+
+// Writer stores crawls. Write is called concurrently by the write stage,
+// and Close is called once every crawl has been written
+type Writer interface {
+	Write(crawl Crawl) error
+	Close() error
+}
+
+// New creates the writer chosen by the output option, which writes to the storage option
+func New() (Writer, error) {
+	switch common.Options.Storage {
+	case "local":
+		return newOutput(nil)
+	case "s3":
+		s3, err := NewS3()
+		if err != nil {
+			return nil, err
+		}
+
+		if common.Options.Output == "protobuf" {
+			return &S3Files{s3: s3}, nil
+		}
+
+		writer, err := newOutput(s3.uploadFiles)
+		if err != nil {
+			return nil, err
+		}
+
+		return uploaded{Writer: writer, s3: s3}, nil
+	}
+
+	return nil, fmt.Errorf("unknown storage: %v", common.Options.Storage)
+}
+
+// newOutput creates the writer chosen by the output option, which calls rotated with the files it finishes
+func newOutput(rotated func(files ...string)) (Writer, error) {
+	switch common.Options.Output {
+	case "segments":
+		return NewSegments(rotated)
+	case "protobuf":
+		return NewFiles()
+	case "warc":
+		return NewWarc(rotated)
+	}
+
+	return nil, fmt.Errorf("unknown output: %v", common.Options.Output)
+}
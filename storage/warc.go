@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/CelestialCrafter/crawler/common"
+	pb "github.com/CelestialCrafter/crawler/protos"
+	"github.com/CelestialCrafter/crawler/warc"
+)
+
+// headers that describe the encoding of the body as sent, which no longer apply once it was decoded
+var decodedHeaders = []string{"content-encoding", "content-length", "transfer-encoding"}
+
+// Warc writes crawls as WARC records
+type Warc struct {
+	writer *warc.Writer
+}
+
+func NewWarc(rotated func(files ...string)) (*Warc, error) {
+	writer, err := warc.NewWriter(
+		path.Join(common.Options.DataPath, "warc"),
+		common.Options.WarcPrefix,
+		fileWorkerId(),
+		common.Options.WarcMaxSize,
+		[]warc.Field{
+			{Name: "software", Value: "crawler"},
+			{Name: "http-header-user-agent", Value: common.Options.UserAgent},
+		},
+		rotated,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Warc{writer: writer}, nil
+}
+
+func (w *Warc) Write(crawl Crawl) error {
+	return w.writer.Write(warcRecords(crawl))
+}
+
+func (w *Warc) Close() error {
+	return w.writer.Close()
+}
+
+// httpMessage formats a start line, headers, and body the way they'd be sent over http/1.1
+func httpMessage(start string, headers map[string]string, body []byte) []byte {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b bytes.Buffer
+	b.WriteString(start + "\r\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "%v: %v\r\n", http.CanonicalHeaderKey(name), headers[name])
+	}
+	b.WriteString("\r\n")
+	b.Write(body)
+
+	return b.Bytes()
+}
+
+// warcRequest reconstructs the request sent for a crawl's final location
+func warcRequest(crawl Crawl) []byte {
+	headers := map[string]string{
+		"host":       crawl.Location.Host,
+		"user-agent": common.Options.UserAgent,
+	}
+	if etag := crawl.Document.Metadata.GetEtag(); etag != "" && crawl.Unchanged {
+		headers["if-none-match"] = etag
+	}
+	if lastModified := crawl.Document.Metadata.GetLastModified(); lastModified != "" && crawl.Unchanged {
+		headers["if-modified-since"] = lastModified
+	}
+
+	return httpMessage(fmt.Sprintf("GET %v HTTP/1.1", crawl.Location.RequestURI()), headers, nil)
+}
+
+// warcResponse reconstructs a response from its recorded headers, with the decoded body
+func warcResponse(response *pb.Response, body []byte, extra map[string]string) []byte {
+	headers := make(map[string]string)
+	for name, value := range response.GetHeaders() {
+		if !slices.Contains(decodedHeaders, name) {
+			headers[name] = value
+		}
+	}
+	for name, value := range extra {
+		headers[name] = value
+	}
+
+	if body != nil {
+		headers["content-length"] = fmt.Sprint(len(body))
+	}
+
+	status := int(response.GetStatus())
+	return httpMessage(fmt.Sprintf("HTTP/1.1 %d %v", status, http.StatusText(status)), headers, body)
+}
+
+// warcMetadata describes how a document was crawled, in the fields heritrix uses
+func warcMetadata(crawl Crawl) []byte {
+	var b strings.Builder
+	field := func(name string, value string) {
+		fmt.Fprintf(&b, "%v: %v\r\n", name, value)
+	}
+
+	field("hopsFromSeed", fmt.Sprint(crawl.Depth))
+	if response := crawl.Document.Metadata.Response; response != nil {
+		field("fetchTimeMs", fmt.Sprint(response.TotalTime.AsDuration().Milliseconds()))
+	}
+	if encoding := crawl.Document.Metadata.GetEncoding(); encoding != "" {
+		field("charsetForLinks", encoding)
+	}
+	for _, child := range crawl.Document.Children {
+		field("outlink", child)
+	}
+
+	return []byte(b.String())
+}
+
+// warcRecords returns the records of a crawled document:
+// the redirects that were followed, then the request, response or revisit, and metadata of its final location
+func warcRecords(crawl Crawl) []warc.Record {
+	metadata := crawl.Document.Metadata
+	response := metadata.Response
+	date := metadata.CrawledAt.AsTime()
+	records := make([]warc.Record, 0)
+
+	for i, redirect := range response.GetRedirects() {
+		next := response.FinalUrl
+		if i+1 < len(response.Redirects) {
+			next = response.Redirects[i+1].Url
+		}
+
+		hop := &pb.Response{Status: redirect.Status}
+		records = append(records, warc.Record{
+			Type:        warc.TYPE_RESPONSE,
+			TargetUri:   redirect.Url,
+			Date:        date,
+			ContentType: "application/http;msgtype=response",
+			Block:       warcResponse(hop, []byte{}, map[string]string{"location": next}),
+			Capture:     &warc.Capture{Status: int(redirect.Status), Mime: "unk", Digest: warc.Digest(nil)},
+		})
+	}
+
+	target := crawl.Location.String()
+	fields := []warc.Field{{Name: "WARC-IP-Address", Value: response.GetRemoteIp()}}
+	record := warc.Record{
+		Id:        warc.NewRecordId(),
+		TargetUri: target,
+		Date:      date,
+		Fields:    fields,
+	}
+
+	switch {
+	case crawl.Unchanged:
+		// https://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/#revisit
+		record.Type = warc.TYPE_REVISIT
+		record.ContentType = "application/http;msgtype=response"
+		record.Fields = append(
+			record.Fields,
+			warc.Field{Name: "WARC-Profile", Value: warc.PROFILE_SERVER_NOT_MODIFIED},
+			warc.Field{Name: "WARC-Refers-To-Target-URI", Value: target},
+		)
+		record.Block = warcResponse(response, nil, nil)
+		record.Capture = &warc.Capture{Status: int(response.GetStatus())}
+	case crawl.Document.DuplicateOf != nil:
+		// https://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/#profile-identical-payload-digest
+		digest := warc.Digest(crawl.Document.Original)
+		record.Type = warc.TYPE_REVISIT
+		record.ContentType = "application/http;msgtype=response"
+		record.Fields = append(
+			record.Fields,
+			warc.Field{Name: "WARC-Profile", Value: warc.PROFILE_IDENTICAL_PAYLOAD_DIGEST},
+			warc.Field{Name: "WARC-Refers-To-Target-URI", Value: *crawl.Document.DuplicateOf},
+			warc.Field{Name: "WARC-Payload-Digest", Value: digest},
+		)
+		record.Block = warcResponse(response, nil, nil)
+		record.Capture = &warc.Capture{Status: int(response.GetStatus()), Digest: digest}
+	default:
+		body := crawl.Document.Original
+		extra := map[string]string{}
+		if crawl.Document.RedirectTo != nil {
+			extra["location"] = *crawl.Document.RedirectTo
+			body = []byte{}
+		}
+
+		digest := warc.Digest(body)
+		record.Type = warc.TYPE_RESPONSE
+		record.ContentType = "application/http;msgtype=response"
+		record.Fields = append(record.Fields, warc.Field{Name: "WARC-Payload-Digest", Value: digest})
+		if crawl.Document.Truncated {
+			record.Fields = append(record.Fields, warc.Field{Name: "WARC-Truncated", Value: "length"})
+		}
+		record.Block = warcResponse(response, body, extra)
+
+		mime := metadata.Mime
+		if mime == "" {
+			mime = "unk"
+		}
+		record.Capture = &warc.Capture{Status: int(response.GetStatus()), Mime: mime, Digest: digest}
+	}
+
+	concurrent := warc.Field{Name: "WARC-Concurrent-To", Value: record.Id}
+	records = append(
+		records,
+		warc.Record{
+			Type:        warc.TYPE_REQUEST,
+			TargetUri:   target,
+			Date:        date,
+			ContentType: "application/http;msgtype=request",
+			Fields:      []warc.Field{concurrent},
+			Block:       warcRequest(crawl),
+		},
+		record,
+		warc.Record{
+			Type:        warc.TYPE_METADATA,
+			TargetUri:   target,
+			Date:        date,
+			ContentType: "application/warc-fields",
+			Fields:      []warc.Field{concurrent},
+			Block:       warcMetadata(crawl),
+		},
+	)
+
+	return records
+}
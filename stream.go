@@ -0,0 +1,360 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/hashicorp/go-metrics"
+
+	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/frontier"
+	"github.com/CelestialCrafter/crawler/parsers"
+	"github.com/CelestialCrafter/crawler/parsers/basic"
+	"github.com/CelestialCrafter/crawler/pipeline"
+	pb "github.com/CelestialCrafter/crawler/protos"
+	"github.com/CelestialCrafter/crawler/scope"
+	"github.com/CelestialCrafter/crawler/storage"
+	"github.com/CelestialCrafter/crawler/urlnorm"
+)
+
+const IDLE_DELAY = 500 * time.Millisecond
+
+// stream continuously feeds the crawl pipeline from the frontier,
+// keeping at most max_in_flight urls between being claimed and finished
+type stream struct {
+	front frontier.Frontier
+	scope *scope.Scope
+	slots chan struct{}
+	input chan pipeline.Result[*crawlDataContext]
+
+	// held for a whole flush, so the producer's flush waits for the ticker's
+	// to reach the frontier before deciding if the crawl is finished
+	flushMu sync.Mutex
+
+	mu       sync.Mutex
+	done     []frontier.Done
+	failures []frontier.Failure
+	children []frontier.Entry
+}
+
+func newStream(front frontier.Frontier, sc *scope.Scope) *stream {
+	return &stream{
+		front: front,
+		scope: sc,
+		slots: make(chan struct{}, common.Options.MaxInFlight),
+		input: make(chan pipeline.Result[*crawlDataContext], common.Options.MaxInFlight),
+	}
+}
+
+// claim blocks until a slot is free, then takes every free slot
+func (s *stream) claim() int {
+	s.slots <- struct{}{}
+	n := 1
+	for {
+		select {
+		case s.slots <- struct{}{}:
+			n++
+		default:
+			return n
+		}
+	}
+}
+
+func (s *stream) release(n int) {
+	for i := 0; i < n; i++ {
+		<-s.slots
+	}
+}
+
+// finish buffers a url for acknowledgement, and frees its slot
+func (s *stream) finish(done frontier.Done, children []frontier.Entry) {
+	s.mu.Lock()
+	s.done = append(s.done, done)
+	s.children = append(s.children, children...)
+	s.mu.Unlock()
+
+	s.release(1)
+}
+
+// fail buffers a url that couldn't be crawled, and frees its slot
+func (s *stream) fail(failure frontier.Failure) {
+	s.mu.Lock()
+	s.failures = append(s.failures, failure)
+	s.mu.Unlock()
+
+	s.release(1)
+}
+
+// discover buffers urls which weren't found on a page in flight
+func (s *stream) discover(entries []frontier.Entry) {
+	s.mu.Lock()
+	s.children = append(s.children, entries...)
+	s.mu.Unlock()
+}
+
+// produce claims urls from the frontier as slots free up,
+// and closes the pipeline input once the frontier is drained
+func (s *stream) produce() {
+	defer close(s.input)
+
+	for {
+		n := s.claim()
+		batch, err := s.front.NextBatch(n)
+		if err != nil {
+			log.Fatal("unable to load new batch", "error", err)
+		}
+		s.release(n - len(batch))
+
+		if len(batch) < 1 {
+			if len(s.slots) > 0 || pendingSitemaps.Load() > 0 {
+				// children of in-flight urls, or urls from sitemaps being discovered, may still refill the queue
+				time.Sleep(IDLE_DELAY)
+				continue
+			}
+
+			s.flush()
+			stats, err := s.front.Stats()
+			if err != nil {
+				log.Fatal("unable to get frontier stats", "error", err)
+			}
+
+			// urls leased by other workers may still have children
+			if stats.Queued < 1 && stats.InFlight < 1 && stats.Delayed < 1 && stats.Scheduled < 1 {
+				log.Warn("no new urls to be crawled; breaking.")
+				return
+			}
+
+			time.Sleep(IDLE_DELAY)
+			continue
+		}
+
+		for _, entry := range batch {
+			data, err := newCrawlDataContext(entry)
+			if err != nil {
+				log.Warn("error parsing url", "error", err)
+				s.fail(frontier.Failure{Url: entry.Url, Err: err})
+				continue
+			}
+
+			s.input <- pipeline.Result[*crawlDataContext]{Item: &data}
+		}
+	}
+}
+
+// contentHash hashes the text of a document, or its original content if it has no text,
+// so markup that changes on every request doesn't count as a change.
+// redirects are hashed by their target
+func contentHash(document *pb.Document) string {
+	content := document.Text
+	if len(content) < 1 {
+		content = document.Original
+	}
+	if document.RedirectTo != nil {
+		content = []byte(*document.RedirectTo)
+	}
+
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+func (s *stream) consume(result pipeline.Result[*crawlDataContext]) {
+	if result.Item == nil {
+		// the pipeline always passes items through, so this shouldn't be reachable
+		log.Error("error pipelining without an item", "error", result.Err)
+		return
+	}
+
+	item := *result.Item
+	var notDue *notDueError
+	if errors.As(result.Err, &notDue) {
+		log.Debug("host not due before the lease expires", "item", item.document.Url, "due", notDue.due)
+		s.fail(frontier.Failure{
+			Url:       item.document.Url,
+			Err:       result.Err,
+			NotBefore: notDue.due,
+			Deferred:  true,
+		})
+		return
+	}
+
+	if result.Err != nil {
+		retryable := parsers.IsRetryable(result.Err)
+		log.Warn("error pipelining", "error", result.Err, "retryable", retryable)
+		metrics.IncrCounterWithLabels(
+			[]string{"failed_count"},
+			1,
+			[]metrics.Label{{
+				Name:  "domain",
+				Value: item.url.Hostname(),
+			}},
+		)
+
+		failure := frontier.Failure{
+			Url:       item.document.Url,
+			Err:       result.Err,
+			Retryable: retryable,
+		}
+
+		// the frontier honors the whole Retry-After, while workers only sleep up to max_crawl_delay
+		if retryAfter := parsers.RetryAfter(result.Err); retryAfter > 0 {
+			failure.NotBefore = time.Now().Add(retryAfter)
+		}
+
+		s.fail(failure)
+		return
+	}
+
+	done := frontier.Done{
+		Url:       item.document.Url,
+		CrawledAt: item.document.Metadata.CrawledAt.AsTime(),
+		Validators: frontier.Validators{
+			ETag:         item.document.Metadata.GetEtag(),
+			LastModified: item.document.Metadata.GetLastModified(),
+		},
+	}
+
+	if item.unchanged {
+		log.Info("pipeline result", "item", item.document.Url, "unchanged", true)
+		metrics.IncrCounterWithLabels(
+			[]string{"unchanged_count"},
+			1,
+			[]metrics.Label{{
+				Name:  "domain",
+				Value: item.url.Hostname(),
+			}},
+		)
+
+		s.finish(done, nil)
+		return
+	}
+
+	log.Info("pipeline result", "item", item.document.Url)
+	metrics.IncrCounterWithLabels(
+		[]string{"crawled_count"},
+		1,
+		[]metrics.Label{{
+			Name:  "domain",
+			Value: item.url.Hostname(),
+		}},
+	)
+
+	if item.document.DuplicateOf != nil {
+		log.Debug("duplicate content", "item", item.document.Url, "of", *item.document.DuplicateOf)
+		metrics.IncrCounterWithLabels(
+			[]string{"duplicate_count"},
+			1,
+			[]metrics.Label{{
+				Name:  "domain",
+				Value: item.url.Hostname(),
+			}},
+		)
+	}
+
+	children := make([]frontier.Entry, len(item.document.Children))
+	for i, child := range item.document.Children {
+		children[i] = frontier.Entry{Url: child, Depth: item.depth + 1}
+	}
+
+	// redirect targets aren't another link away from the seeds
+	if item.document.RedirectTo != nil {
+		log.Debug("redirect enqueued", "from", item.document.Url, "to", *item.document.RedirectTo)
+		children = append(children, frontier.Entry{Url: *item.document.RedirectTo, Depth: item.depth})
+	}
+
+	done.Hash = contentHash(&item.document)
+	s.finish(done, children)
+}
+
+// admit normalizes entries, and filters out ones that aren't in the crawl's scope
+func (s *stream) admit(entries []frontier.Entry) []frontier.Entry {
+	admitted := make([]frontier.Entry, 0, len(entries))
+	for _, entry := range entries {
+		u, err := url.Parse(entry.Url)
+		if err == nil {
+			u = urlnorm.Normalize(u)
+			err = s.scope.Check(u, entry.Depth)
+		}
+
+		if err != nil {
+			log.Debug("url not admitted", "url", entry.Url, "reason", err)
+			continue
+		}
+
+		entry.Url = u.String()
+		admitted = append(admitted, entry)
+	}
+
+	return admitted
+}
+
+// flush acknowledges finished urls, reschedules failed urls,
+// and writes the children of finished urls to the frontier
+func (s *stream) flush() {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	done, failures, children := s.done, s.failures, s.children
+	s.done, s.failures, s.children = nil, nil, nil
+	s.mu.Unlock()
+
+	children = s.admit(children)
+
+	if len(done) < 1 && len(failures) < 1 && len(children) < 1 {
+		return
+	}
+
+	start := time.Now()
+
+	// acknowledge first, so urls linking to themselves aren't requeued
+	err := s.front.MarkDone(done)
+	if err != nil {
+		log.Fatal("unable to acknowledge crawled urls", "error", err)
+	}
+
+	err = s.front.Fail(failures)
+	if err != nil {
+		log.Fatal("unable to reschedule failed urls", "error", err)
+	}
+
+	if len(children) > 0 {
+		err = s.front.Enqueue(children)
+		if err != nil {
+			log.Fatal("unable to write new urls", "error", err)
+		}
+	}
+
+	log.Info(
+		"flushed",
+		"crawled", len(done),
+		"failed", len(failures),
+		"children", len(children),
+		"duration", time.Since(start),
+	)
+}
+
+func (s *stream) run(parser parsers.Parser, follow basic.FollowRedirect, writer storage.Writer, index contentIndex) {
+	go s.produce()
+
+	ticker := time.NewTicker(common.Options.FlushInterval)
+	defer ticker.Stop()
+
+	output := crawlPipeline(parser, follow, writer, index, s.input, s.discover)
+	for {
+		select {
+		case result, ok := <-output:
+			if !ok {
+				s.flush()
+				return
+			}
+			s.consume(result)
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CelestialCrafter/crawler/common"
+	"github.com/CelestialCrafter/crawler/frontier"
+	"github.com/CelestialCrafter/crawler/parsers"
+	"github.com/CelestialCrafter/crawler/parsers/basic"
+	"github.com/CelestialCrafter/crawler/pipeline"
+	"github.com/CelestialCrafter/crawler/scope"
+	"github.com/CelestialCrafter/crawler/storage"
+)
+
+func newTestStream(t *testing.T) (*stream, *frontier.Memory) {
+	t.Helper()
+
+	common.Options = common.Default
+	common.Options.WorkerId = "test"
+	common.Options.Workers = 2
+	common.Options.DefaultCrawlDelay = time.Millisecond
+	common.Options.FlushInterval = 50 * time.Millisecond
+
+	front, err := frontier.NewMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc, err := scope.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newStream(front, sc), front
+}
+
+func frontierStats(t *testing.T, front frontier.Frontier) frontier.Stats {
+	t.Helper()
+
+	stats, err := front.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stats
+}
+
+func TestStreamRun(t *testing.T) {
+	s, front := newTestStream(t)
+
+	server := testSite()
+	defer server.Close()
+
+	err := front.Seed([]frontier.Entry{{Url: server.URL + "/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	follow, err := redirectPolicy(s.scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// run returns once the frontier is drained and every result was flushed
+	writer := &memoryWriter{crawls: make(map[string]storage.Crawl)}
+	finished := make(chan struct{})
+	go func() {
+		s.run(basic.New(follow), follow, writer, newMemoryContentIndex())
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(10 * time.Second):
+		t.Fatal("stream didn't exit after the frontier was drained")
+	}
+
+	// children are normalized before they are enqueued
+	for _, p := range []string{"/", "/a", "/b"} {
+		if _, ok := writer.crawls[server.URL+p]; !ok {
+			t.Errorf("%v wasn't crawled", p)
+		}
+	}
+
+	stats := frontierStats(t, front)
+	if stats != (frontier.Stats{Crawled: 3}) {
+		t.Errorf("stats = %+v, want the 3 pages to be crawled", stats)
+	}
+}
+
+func TestStreamConsume(t *testing.T) {
+	s, front := newTestStream(t)
+
+	urls := []string{"http://a.com/", "http://a.com/missing", "http://a.com/busy", "http://a.com/moved"}
+	entries := make([]frontier.Entry, len(urls))
+	for i, u := range urls {
+		entries[i] = frontier.Entry{Url: u, Depth: 1}
+	}
+
+	err := front.Seed(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch, err := front.NextBatch(len(urls))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(map[string]*crawlDataContext)
+	for _, entry := range batch {
+		data, err := newCrawlDataContext(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results[entry.Url] = data
+	}
+
+	results["http://a.com/"].document.Children = []string{"http://a.com/child", "http://b.com/"}
+	moved := "http://a.com/new"
+	results["http://a.com/moved"].document.RedirectTo = &moved
+
+	result := func(u string, err error) pipeline.Result[*crawlDataContext] {
+		data := results[u]
+		return pipeline.Result[*crawlDataContext]{Item: &data, Err: err}
+	}
+
+	// the urls hold a slot each until they're consumed
+	s.release(s.claim() - len(urls))
+	s.consume(result("http://a.com/", nil))
+	s.consume(result("http://a.com/moved", nil))
+	s.consume(result("http://a.com/missing", &parsers.StatusError{StatusCode: http.StatusNotFound}))
+	s.consume(result("http://a.com/busy", &parsers.StatusError{StatusCode: http.StatusServiceUnavailable, RetryAfter: time.Hour}))
+
+	if len(s.slots) != 0 {
+		t.Errorf("%v slots are still claimed, want every consumed url to free its slot", len(s.slots))
+	}
+
+	// children are one link deeper, while redirect targets keep their source's depth
+	depths := make(map[string]int)
+	for _, child := range s.children {
+		depths[child.Url] = child.Depth
+	}
+	if len(depths) != 3 || depths["http://a.com/child"] != 2 || depths["http://b.com/"] != 2 || depths[moved] != 1 {
+		t.Errorf("children = %+v, want the links at depth 2 and the redirect at depth 1", s.children)
+	}
+
+	var busy frontier.Failure
+	for _, failure := range s.failures {
+		if failure.Url == "http://a.com/busy" {
+			busy = failure
+		}
+	}
+	if !busy.Retryable || time.Until(busy.NotBefore) < 59*time.Minute {
+		t.Errorf("failure = %+v, want a retry after the Retry-After", busy)
+	}
+
+	s.flush()
+	stats := frontierStats(t, front)
+	if stats.Crawled != 2 || stats.Dead != 1 || stats.Delayed != 1 || stats.Queued != 3 || stats.InFlight != 0 {
+		t.Errorf("stats = %+v, want 2 crawled, 1 dead, 1 delayed, and 3 children queued", stats)
+	}
+
+	if len(s.done) != 0 || len(s.failures) != 0 || len(s.children) != 0 {
+		t.Error("flush didn't clear the buffered results")
+	}
+}
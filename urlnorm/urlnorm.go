@@ -0,0 +1,206 @@
+package urlnorm
+
+import (
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+const UPPER_HEX = "0123456789ABCDEF"
+
+// DefaultPorts are the ports implied by each scheme when a url doesn't have one
+var DefaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+
+	return 0, false
+}
+
+// https://www.rfc-editor.org/rfc/rfc3986#section-2.3
+func unreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' ||
+		'A' <= c && c <= 'Z' ||
+		'0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// normalizeEscapes decodes percent-encoded unreserved characters,
+// and uppercases the hex digits of every other escape
+// https://www.rfc-editor.org/rfc/rfc3986#section-6.2.2.2
+func normalizeEscapes(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		hi, ok1 := unhex(s[i+1])
+		lo, ok2 := unhex(s[i+2])
+		if !ok1 || !ok2 {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		c := hi<<4 | lo
+		if unreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteByte(UPPER_HEX[hi])
+			b.WriteByte(UPPER_HEX[lo])
+		}
+		i += 2
+	}
+
+	return b.String()
+}
+
+// removeDotSegments resolves "." and ".." segments in an absolute path
+// https://www.rfc-editor.org/rfc/rfc3986#section-5.2.4
+func removeDotSegments(p string) string {
+	segments := strings.Split(p, "/")
+	out := make([]string, 0, len(segments))
+	last := len(segments) - 1
+
+	for i, segment := range segments {
+		switch segment {
+		case ".":
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, segment)
+			continue
+		}
+
+		// keep the trailing slash of paths ending in a dot segment
+		if i == last {
+			out = append(out, "")
+		}
+	}
+
+	return strings.Join(out, "/")
+}
+
+// stripped reports if a query parameter is a tracking parameter or session id
+func stripped(key string) bool {
+	key = strings.ToLower(key)
+	return slices.ContainsFunc(common.Options.StripQueryParams, func(pattern string) bool {
+		matched, _ := path.Match(strings.ToLower(pattern), key)
+		return matched
+	})
+}
+
+// stripPathParams removes stripped ";key=value" parameters from each segment of a path, like ";jsessionid=..."
+func stripPathParams(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		name, params, ok := strings.Cut(segment, ";")
+		if !ok {
+			continue
+		}
+
+		kept := []string{name}
+		for _, param := range strings.Split(params, ";") {
+			key, _, _ := strings.Cut(param, "=")
+			if !stripped(key) {
+				kept = append(kept, param)
+			}
+		}
+
+		segments[i] = strings.Join(kept, ";")
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func normalizeQuery(rawQuery string) string {
+	params := make([]string, 0)
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+
+		param = normalizeEscapes(param)
+		key, _, _ := strings.Cut(param, "=")
+		unescaped, err := url.QueryUnescape(key)
+		if err != nil {
+			unescaped = key
+		}
+
+		if !stripped(unescaped) {
+			params = append(params, param)
+		}
+	}
+
+	// values of repeated keys keep their order
+	slices.SortStableFunc(params, func(a, b string) int {
+		a, _, _ = strings.Cut(a, "=")
+		b, _, _ = strings.Cut(b, "=")
+		return strings.Compare(a, b)
+	})
+
+	return strings.Join(params, "&")
+}
+
+// Normalize returns the canonical form of an absolute url
+func Normalize(u *url.URL) *url.URL {
+	n := *u
+	n.Fragment = ""
+	n.RawFragment = ""
+
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Host = strings.ToLower(n.Hostname())
+	if strings.Contains(n.Host, ":") {
+		// ipv6
+		n.Host = "[" + n.Host + "]"
+	}
+	if port := u.Port(); port != "" && port != DefaultPorts[n.Scheme] {
+		n.Host += ":" + port
+	}
+
+	escapedPath := u.EscapedPath()
+	if escapedPath == "" && n.Host != "" {
+		escapedPath = "/"
+	}
+	escapedPath = stripPathParams(removeDotSegments(normalizeEscapes(escapedPath)))
+
+	unescapedPath, err := url.PathUnescape(escapedPath)
+	if err == nil {
+		n.Path = unescapedPath
+		n.RawPath = escapedPath
+	}
+
+	n.RawQuery = normalizeQuery(n.RawQuery)
+	n.ForceQuery = false
+
+	return &n
+}
+
+// NormalizeString parses and normalizes a url
+func NormalizeString(rawUrl string) (string, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", err
+	}
+
+	return Normalize(u).String(), nil
+}
@@ -0,0 +1,60 @@
+package urlnorm
+
+import (
+	"testing"
+
+	"github.com/CelestialCrafter/crawler/common"
+)
+
+func TestStripPathParams(t *testing.T) {
+	common.Options.StripQueryParams = common.Default.StripQueryParams
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/a/b", "/a/b"},
+		{"/a;jsessionid=1", "/a"},
+		{"/a;jsessionid=1/b", "/a/b"},
+		{"/a;jsessionid=1/b;phpsessid=2", "/a/b"},
+		{"/a;v=1;jsessionid=2/b", "/a;v=1/b"},
+		{"/a;v=1/b;jsessionid=2", "/a;v=1/b"},
+		{"/a;jsessionid=1/", "/a/"},
+		{"/a;JSESSIONID=1/b", "/a/b"},
+	}
+
+	for _, test := range tests {
+		got := stripPathParams(test.path)
+		if got != test.want {
+			t.Errorf("stripPathParams(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeString(t *testing.T) {
+	common.Options.StripQueryParams = common.Default.StripQueryParams
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://x.com/a;jsessionid=1/b", "http://x.com/a/b"},
+		{"HTTP://X.com:80/a/./b/../c#top", "http://x.com/a/c"},
+		{"https://x.com/?b=2&utm_source=y&a=1", "https://x.com/?a=1&b=2"},
+		{"http://x.com/%7euser", "http://x.com/~user"},
+		{"https://x.com:443/", "https://x.com/"},
+		{"https://x.com:8443/", "https://x.com:8443/"},
+	}
+
+	for _, test := range tests {
+		got, err := NormalizeString(test.url)
+		if err != nil {
+			t.Errorf("NormalizeString(%q) returned error: %v", test.url, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("NormalizeString(%q) = %q, want %q", test.url, got, test.want)
+		}
+	}
+}
@@ -561,131 +561,423 @@ function URL:normalize()
 	return self
 end
 
-local function countHosts(urls)
-	local counts = {}
-	for _, url in ipairs(urls) do
-		counts[url.host] = (counts[url.host] or 0) + 1
-	end
-	return counts
-end
+-- every scorer receives the same variables, and higher scores are popped first
+-- - `depth` is the amount of links followed from a seed url
+-- - `inlinks` is the amount of times the url has been enqueued
+-- - `lasthit` is the time the url's host was last popped, in unix seconds
+-- - `now` is the current time, in unix seconds
+-- - `priority` is the url's sitemap priority, or 0.5 if it wasn't in a sitemap
+-- - `lastmod` is the url's sitemap last modification time in unix seconds, or 0 if unknown
+local scorers = {
+	-- breadth first search
+	depth = function(vars)
+		return -vars.depth
+	end,
+	inlinks = function(vars)
+		return vars.inlinks
+	end,
+	-- hosts that were hit the longest time ago first
+	recency = function(vars)
+		return vars.now - vars.lasthit
+	end,
+	-- highest sitemap priority first
+	sitemap = function(vars)
+		return vars.priority
+	end,
+}
 
-local function mean(counts)
-	local sum = 0
-	local count = 0
-	for k, v in pairs(counts) do
-		sum = sum + v
-		count = count + 1
+local function formulaScorer(formula)
+	if not loadstring or not setfenv then
+		return nil, "formula prioritization is not supported by this server"
 	end
-	local meanCount = sum / count
 
-	return function(a, b)
-		local distA = math.abs(counts[a] - meanCount)
-		local distB = math.abs(counts[b] - meanCount)
+	local chunk, err = loadstring("return " .. formula)
+	if not chunk then
+		return nil, "unable to compile queue priority formula: " .. err
+	end
 
-		return distA < distB
+	return function(vars)
+		setfenv(chunk, setmetatable(vars, { __index = { math = math } }))
+		return tonumber(chunk()) or 0
 	end
 end
 
-local function asc(counts)
-	return function(a, b)
-		return counts[a] < counts[b]
+local function getScorer(method, formula)
+	method = string.lower(method)
+	if method == "formula" then
+		return formulaScorer(formula)
 	end
-end
 
-local function desc(counts)
-	return function(a, b)
-		return counts[a] > counts[b]
+	local scorer = scorers[method]
+	if not scorer then
+		return nil, "unknown queue prioritization: " .. method
 	end
+
+	return scorer
 end
 
-local function sortHosts(counts, method)
-	local hosts = {}
-	for host in pairs(counts) do
-		table.insert(hosts, host)
+local function scoreOf(scorer, vars)
+	local score = scorer(vars)
+	-- nan can't be stored in a sorted set
+	if score ~= score then
+		return 0
 	end
-	table.sort(hosts, method(counts))
-	return hosts
+
+	return score
 end
 
-local function distributeUrls(urls, method)
-	local counts = countHosts(urls)
-	local sortedHosts = sortHosts(counts, method)
+local function urlVars(url, host, now)
+	return {
+		depth = tonumber(redis.call("HGET", "depth", url)) or 0,
+		inlinks = tonumber(redis.call("HGET", "inlinks", url)) or 0,
+		lasthit = tonumber(redis.call("HGET", "hosts", host)) or 0,
+		now = now,
+		priority = tonumber(redis.call("HGET", "priority", url)) or 0.5,
+		lastmod = tonumber(redis.call("HGET", "lastmod", url)) or 0,
+	}
+end
 
-	-- group urls by host
-	local groupedUrls = {}
-	for _, url in ipairs(urls) do
-		if not groupedUrls[url.host] then
-			groupedUrls[url.host] = {}
+-- seen filters remember which urls have been crawled
+-- - `exact` stores every url in the crawled set
+-- - `bloom` sets bits in the seen bitmap, and counts urls in seencount.
+--   it uses a fixed amount of memory, but treats a fraction of unseen urls as crawled
+local seenFilters = {
+	exact = function()
+		return {
+			add = function(url)
+				redis.call("SADD", "crawled", url)
+			end,
+			has = function(url)
+				return redis.call("SISMEMBER", "crawled", url) == 1
+			end,
+		}
+	end,
+	-- https://www.eecs.harvard.edu/~michaelm/postscripts/rsa2008.pdf
+	bloom = function(bits, hashes)
+		local function offsets(url)
+			local digest = redis.sha1hex(url)
+			local h1 = tonumber(string.sub(digest, 1, 8), 16)
+			local h2 = tonumber(string.sub(digest, 9, 16), 16)
+
+			local result = {}
+			for i = 0, hashes - 1 do
+				result[i + 1] = (h1 + i * h2) % bits
+			end
+			return result
 		end
-		table.insert(groupedUrls[url.host], url)
+
+		return {
+			add = function(url)
+				for _, offset in ipairs(offsets(url)) do
+					redis.call("SETBIT", "seen", offset, 1)
+				end
+				redis.call("INCR", "seencount")
+			end,
+			has = function(url)
+				for _, offset in ipairs(offsets(url)) do
+					if redis.call("GETBIT", "seen", offset) == 0 then
+						return false
+					end
+				end
+				return true
+			end,
+		}
+	end,
+}
+
+local function getSeenFilter(kind, bits, hashes)
+	local filter = seenFilters[kind]
+	if not filter then
+		return nil, "unknown seen filter: " .. kind
 	end
 
-	-- distribute urls
-	local result = {}
-	local index = 1
-	while #sortedHosts > 0 do
-		local host = table.remove(sortedHosts, 1)
-		while #groupedUrls[host] > 0 do
-			table.insert(result, table.remove(groupedUrls[host], 1))
+	return filter(tonumber(bits), tonumber(hashes))
+end
 
-			index = index + 1
-			if index > #sortedHosts then
-				index = 1
+-- args: prioritization, formula, now, seen filter, seen bits, seen hashes,
+-- then url, depth, priority, and lastmod quadruples.
+-- priority and lastmod are empty strings when the url wasn't found in a sitemap
+redis.register_function("QUEUEPUSH", function(_, args)
+	local scorer, err = getScorer(args[1], args[2])
+	if not scorer then
+		return redis.error_reply(err)
+	end
+
+	local seen, seenErr = getSeenFilter(args[4], args[5], args[6])
+	if not seen then
+		return redis.error_reply(seenErr)
+	end
+
+	local now = tonumber(args[3])
+	local added = 0
+
+	for i = 7, #args, 4 do
+		local url = args[i]
+		local depth = tonumber(args[i + 1])
+		local priority = args[i + 2]
+		local lastmod = args[i + 3]
+		local host = URL.parse(url).host
+
+		if
+			host
+			and not seen.has(url)
+			and not redis.call("ZSCORE", "inflight", url)
+			and not redis.call("ZSCORE", "delayed", url)
+			and redis.call("HEXISTS", "dead", url) == 0
+		then
+			local oldDepth = tonumber(redis.call("HGET", "depth", url))
+			if not oldDepth or depth < oldDepth then
+				redis.call("HSET", "depth", url, depth)
+			end
+			redis.call("HINCRBY", "inlinks", url, 1)
+			if priority ~= "" then
+				redis.call("HSET", "priority", url, priority)
 			end
+			if lastmod ~= "" then
+				redis.call("HSET", "lastmod", url, lastmod)
+			end
+
+			redis.call("ZADD", "queue", scoreOf(scorer, urlVars(url, host, now)), url)
+			added = added + 1
 		end
 	end
 
-	return result
-end
+	return added
+end)
 
-local function slice(tbl, first, last, step)
-	local sliced = {}
+-- moves urls with expired leases back into the queue
+local function reap(scorer, now)
+	local expired = redis.call("ZRANGEBYSCORE", "inflight", "-inf", now)
+	for _, url in ipairs(expired) do
+		redis.call("ZREM", "inflight", url)
+		redis.call("HDEL", "leases", url)
 
-	for i = first or 1, last or #tbl, step or 1 do
-		sliced[#sliced + 1] = tbl[i]
+		local host = URL.parse(url).host
+		redis.call("ZADD", "queue", scoreOf(scorer, urlVars(url, host, now)), url)
 	end
 
-	return sliced
+	return #expired
 end
 
-local function map(tbl, f)
-	local t = {}
-	for k, v in pairs(tbl) do
-		t[k] = f(v)
+-- moves retried or recrawled urls that are due back into the queue
+local function promote(scorer, now, key)
+	local due = redis.call("ZRANGEBYSCORE", key, "-inf", now)
+	for _, url in ipairs(due) do
+		redis.call("ZREM", key, url)
+
+		local host = URL.parse(url).host
+		redis.call("ZADD", "queue", scoreOf(scorer, urlVars(url, host, now)), url)
 	end
-	return t
+
+	return #due
 end
 
-local function filter(tbl, f)
-	local t = {}
-	for k, v in pairs(tbl) do
-		if f(v, k, t) then
-			t[k] = v
+-- args: prioritization, formula, now
+-- returns the amount of urls that were requeued
+redis.register_function("QUEUEREAP", function(_, args)
+	local scorer, err = getScorer(args[1], args[2])
+	if not scorer then
+		return redis.error_reply(err)
+	end
+
+	return reap(scorer, tonumber(args[3]))
+end)
+
+-- args: prioritization, formula, now, batch size, worker id, lease duration in seconds
+-- returns url, depth, etag, and last modified quadruples.
+-- etag and last modified are empty strings unless the url is being recrawled
+redis.register_function("QUEUEPOP", function(_, args)
+	local scorer, err = getScorer(args[1], args[2])
+	if not scorer then
+		return redis.error_reply(err)
+	end
+
+	local now = tonumber(args[3])
+	local n = tonumber(args[4])
+	local worker = args[5]
+	local deadline = now + tonumber(args[6])
+
+	reap(scorer, now)
+	promote(scorer, now, "delayed")
+	promote(scorer, now, "recrawl")
+
+	-- scores go stale as hosts get hit, so oversample and rescore the candidates
+	local popped = redis.call("ZPOPMAX", "queue", n * 5)
+	local candidates = {}
+	local lastHits = {}
+	for i = 1, #popped, 2 do
+		local url = popped[i]
+		local host = URL.parse(url).host
+		local vars = urlVars(url, host, now)
+
+		lastHits[host] = vars.lasthit
+		table.insert(candidates, { url = url, host = host, vars = vars })
+	end
+
+	local batch = {}
+	while #batch < n * 4 and #candidates > 0 do
+		local best, bestScore
+		for i, candidate in ipairs(candidates) do
+			candidate.vars.lasthit = lastHits[candidate.host]
+			local score = scoreOf(scorer, candidate.vars)
+			if not best or score > bestScore then
+				best = i
+				bestScore = score
+			end
 		end
+
+		local candidate = table.remove(candidates, best)
+		lastHits[candidate.host] = now
+		redis.call("HSET", "hosts", candidate.host, now)
+		redis.call("ZADD", "inflight", deadline, candidate.url)
+		redis.call("HSET", "leases", candidate.url, worker)
+
+		table.insert(batch, candidate.url)
+		table.insert(batch, tostring(candidate.vars.depth))
+		table.insert(batch, redis.call("HGET", "etag", candidate.url) or "")
+		table.insert(batch, redis.call("HGET", "lastmodified", candidate.url) or "")
+	end
+
+	for _, candidate in ipairs(candidates) do
+		candidate.vars.lasthit = lastHits[candidate.host]
+		redis.call("ZADD", "queue", scoreOf(scorer, candidate.vars), candidate.url)
+	end
+
+	return batch
+end)
+
+local function setOrDelete(key, field, value)
+	if value == "" then
+		redis.call("HDEL", key, field)
+	else
+		redis.call("HSET", key, field, value)
 	end
-	return t
 end
 
-redis.register_function("QUEUESLICE", function(_, args)
-	local queueSlice = redis.call("SRANDMEMBER", "queue", args[2])
+-- mirrors RECRAWL_FACTOR in frontier/recrawl.go
+local RECRAWL_FACTOR = 2
+
+-- urls start at the initial interval, and the interval is halved when their content changed,
+-- or doubled when it didn't. an empty hash means the url was unchanged
+local function nextRecrawlInterval(url, hash, initial, minInterval, maxInterval)
+	local interval = tonumber(redis.call("HGET", "interval", url))
+	if not interval then
+		interval = initial
+	elseif hash == "" or redis.call("HGET", "contenthash", url) == hash then
+		interval = interval * RECRAWL_FACTOR
+	else
+		interval = interval / RECRAWL_FACTOR
+	end
 
-	local methods = {
-		asc = asc,
-		desc = desc,
-		mean = mean,
-	}
+	return math.max(minInterval, math.min(interval, maxInterval))
+end
 
-	return map(
-		slice(
-			distributeUrls(
-				filter(map(queueSlice, URL.parse), function(url)
-					return url.host ~= nil
-				end),
-				methods[string.lower(args[1])]
-			),
-			0,
-			args[3]
-		),
-		URL.build
-	)
+-- args: worker id, seen filter, seen bits, seen hashes,
+-- initial, min, and max recrawl intervals in seconds (0 when recrawling is disabled),
+-- then url, content hash, crawl time, etag, and last modified quintuples.
+-- the content hash is empty when the url was unchanged since its last crawl
+-- urls whose lease expired and were claimed by another worker are left alone
+-- returns the amount of urls that were acknowledged
+redis.register_function("QUEUEACK", function(_, args)
+	local worker = args[1]
+	local seen, err = getSeenFilter(args[2], args[3], args[4])
+	if not seen then
+		return redis.error_reply(err)
+	end
+
+	local initial = tonumber(args[5])
+	local minInterval = tonumber(args[6])
+	local maxInterval = tonumber(args[7])
+	local recrawl = initial > 0
+	local acked = 0
+
+	for i = 8, #args, 5 do
+		local url = args[i]
+		local hash = args[i + 1]
+		local crawledAt = tonumber(args[i + 2])
+		local etag = args[i + 3]
+		local lastModified = args[i + 4]
+
+		if redis.call("HGET", "leases", url) == worker then
+			redis.call("ZREM", "inflight", url)
+			redis.call("HDEL", "leases", url)
+			redis.call("HDEL", "attempts", url)
+			if not seen.has(url) then
+				seen.add(url)
+			end
+
+			if recrawl then
+				-- scoring variables are kept for when the url is recrawled
+				local interval = nextRecrawlInterval(url, hash, initial, minInterval, maxInterval)
+				redis.call("HSET", "interval", url, interval)
+				if hash ~= "" then
+					redis.call("HSET", "contenthash", url, hash)
+				end
+				setOrDelete("etag", url, etag)
+				setOrDelete("lastmodified", url, lastModified)
+				redis.call("ZADD", "recrawl", crawledAt + interval, url)
+			else
+				redis.call("HDEL", "depth", url)
+				redis.call("HDEL", "inlinks", url)
+				redis.call("HDEL", "priority", url)
+				redis.call("HDEL", "lastmod", url)
+			end
+
+			acked = acked + 1
+		end
+	end
+
+	return acked
+end)
+
+-- args: worker id, now, max attempts, base retry delay, max retry delay,
+-- then url, retryable (1 or 0), deferred (1 or 0), not before (0 if unset), and error quintuples.
+-- deferred urls weren't crawled, so they're delayed until not before without using up an attempt.
+-- retryable urls are delayed with exponential backoff, or until not before if it is later,
+-- until they run out of attempts,
+-- after which they are moved to the dead letter hash along with their last error
+-- returns the amount of urls that were moved to the dead letter hash
+redis.register_function("QUEUEFAIL", function(_, args)
+	local worker = args[1]
+	local now = tonumber(args[2])
+	local maxAttempts = tonumber(args[3])
+	local baseDelay = tonumber(args[4])
+	local maxDelay = tonumber(args[5])
+	local dead = 0
+
+	for i = 6, #args, 5 do
+		local url = args[i]
+		local retryable = args[i + 1] == "1"
+		local deferred = args[i + 2] == "1"
+		local notBefore = tonumber(args[i + 3])
+		local err = args[i + 4]
+
+		if redis.call("HGET", "leases", url) == worker then
+			redis.call("ZREM", "inflight", url)
+			redis.call("HDEL", "leases", url)
+
+			if deferred then
+				redis.call("ZADD", "delayed", notBefore, url)
+			else
+				local attempts = redis.call("HINCRBY", "attempts", url, 1)
+				if retryable and attempts < maxAttempts then
+					local delay = math.min(baseDelay * 2 ^ (attempts - 1), maxDelay)
+					redis.call("ZADD", "delayed", math.max(now + delay, notBefore), url)
+				else
+					redis.call("HSET", "dead", url, err)
+					redis.call("HDEL", "depth", url)
+					redis.call("HDEL", "inlinks", url)
+					redis.call("HDEL", "priority", url)
+					redis.call("HDEL", "lastmod", url)
+					redis.call("HDEL", "interval", url)
+					redis.call("HDEL", "contenthash", url)
+					redis.call("HDEL", "etag", url)
+					redis.call("HDEL", "lastmodified", url)
+					dead = dead + 1
+				end
+			end
+		end
+	end
+
+	return dead
 end)
@@ -0,0 +1,98 @@
+package warc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Surt returns the sort-friendly form of u used as the index key,
+// such as "com,example)/path?a=b" for "https://www.example.com/path?a=b"
+// https://pywb.readthedocs.io/en/latest/manual/indexing.html
+func Surt(u string) (string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	parts := strings.Split(host, ".")
+	slices.Reverse(parts)
+
+	key := strings.Join(parts, ",")
+	if port := parsed.Port(); port != "" && port != "80" && port != "443" {
+		key += ":" + port
+	}
+
+	p := parsed.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+
+	key += ")" + strings.ToLower(p)
+	if parsed.RawQuery != "" {
+		key += "?" + strings.ToLower(parsed.RawQuery)
+	}
+
+	return key, nil
+}
+
+type indexFields struct {
+	Url      string `json:"url"`
+	Mime     string `json:"mime"`
+	Status   string `json:"status"`
+	Digest   string `json:"digest"`
+	Length   string `json:"length"`
+	Offset   string `json:"offset"`
+	Filename string `json:"filename"`
+}
+
+// indexLine returns the CDXJ line of a record written at offset in filename
+// https://specs.webrecorder.net/cdxj/0.1.0/
+func indexLine(record *Record, filename string, offset int64, length int64) (string, error) {
+	key, err := Surt(record.TargetUri)
+	if err != nil {
+		return "", err
+	}
+
+	mime := record.Capture.Mime
+	if record.Type == TYPE_REVISIT {
+		mime = "warc/revisit"
+	}
+
+	status := ""
+	if record.Capture.Status > 0 {
+		status = fmt.Sprint(record.Capture.Status)
+	}
+
+	fields, err := json.Marshal(indexFields{
+		Url:      record.TargetUri,
+		Mime:     mime,
+		Status:   status,
+		Digest:   record.Capture.Digest,
+		Length:   fmt.Sprint(length),
+		Offset:   fmt.Sprint(offset),
+		Filename: filename,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%v %v %s", key, record.Date.UTC().Format("20060102150405"), fields), nil
+}
+
+// writeIndex writes lines to p, sorted so they can be binary searched
+func writeIndex(p string, lines []string) error {
+	slices.Sort(lines)
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	return os.WriteFile(p, []byte(b.String()), 0644)
+}
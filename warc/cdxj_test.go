@@ -0,0 +1,26 @@
+package warc
+
+import "testing"
+
+func TestSurt(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.Example.com/Path?B=2", "com,example)/path?b=2"},
+		{"http://example.com", "com,example)/"},
+		{"http://a.example.com:80/", "com,example,a)/"},
+		{"http://example.com:8080/a%20b", "com,example:8080)/a%20b"},
+	}
+
+	for _, test := range tests {
+		key, err := Surt(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if key != test.want {
+			t.Errorf("Surt(%q) = %q, want %q", test.url, key, test.want)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// Package warc writes crawls as gzipped WARC/1.1 files, with a CDXJ index alongside each one
+// https://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const VERSION = "WARC/1.1"
+
+const (
+	TYPE_WARCINFO = "warcinfo"
+	TYPE_REQUEST  = "request"
+	TYPE_RESPONSE = "response"
+	TYPE_METADATA = "metadata"
+	TYPE_REVISIT  = "revisit"
+)
+
+// https://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/#profile-server-not-modified
+const PROFILE_SERVER_NOT_MODIFIED = "http://netpreserve.org/warc/1.1/revisit/server-not-modified"
+
+// https://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/#profile-identical-payload-digest
+const PROFILE_IDENTICAL_PAYLOAD_DIGEST = "http://netpreserve.org/warc/1.1/revisit/identical-payload-digest"
+
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Capture describes a response or revisit record in the index
+type Capture struct {
+	Status int
+	Mime   string
+	// payload digest, as returned by Digest
+	Digest string
+}
+
+type Record struct {
+	Type string
+	// generated if empty
+	Id          string
+	TargetUri   string
+	Date        time.Time
+	ContentType string
+	// extra named fields, such as WARC-Concurrent-To or WARC-Payload-Digest
+	Fields []Field
+	Block  []byte
+	// records with a capture are added to the index
+	Capture *Capture
+}
+
+// NewRecordId returns a random uuid record id
+// https://www.rfc-editor.org/rfc/rfc9562.html#section-5.4
+func NewRecordId() string {
+	b := make([]byte, 16)
+	// crypto/rand never returns an error on supported platforms
+	_, _ = rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Digest returns the labelled sha1 digest of b, which is what most tools expect
+func Digest(b []byte) string {
+	sum := sha1.Sum(b)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// marshal returns the uncompressed record, and fills in its id
+func (r *Record) marshal() []byte {
+	if r.Id == "" {
+		r.Id = NewRecordId()
+	}
+
+	var b bytes.Buffer
+	field := func(name string, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%v: %v\r\n", name, value)
+		}
+	}
+
+	b.WriteString(VERSION + "\r\n")
+	field("WARC-Type", r.Type)
+	field("WARC-Record-ID", r.Id)
+	field("WARC-Date", r.Date.UTC().Format(time.RFC3339Nano))
+	field("WARC-Target-URI", r.TargetUri)
+	for _, f := range r.Fields {
+		field(f.Name, f.Value)
+	}
+	field("WARC-Block-Digest", Digest(r.Block))
+	field("Content-Type", r.ContentType)
+	field("Content-Length", fmt.Sprint(len(r.Block)))
+	b.WriteString("\r\n")
+	b.Write(r.Block)
+	b.WriteString("\r\n\r\n")
+
+	return b.Bytes()
+}
+
+// Writer appends records to gzipped WARC files in a directory,
+// starting a new file once the current one reaches a max size
+type Writer struct {
+	mu     sync.Mutex
+	dir    string
+	prefix string
+	// what wrote the files, so writers sharing a directory don't pick the same names
+	host    string
+	maxSize int64
+	// added to each file's warcinfo record
+	info []Field
+	// called with a file and its index once they're written, if it isn't nil
+	rotated func(files ...string)
+
+	file   *os.File
+	name   string
+	size   int64
+	serial int
+	index  []string
+}
+
+func NewWriter(dir string, prefix string, host string, maxSize int64, info []Field, rotated func(files ...string)) (*Writer, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		dir:     dir,
+		prefix:  prefix,
+		host:    host,
+		maxSize: maxSize,
+		info:    info,
+		rotated: rotated,
+	}, nil
+}
+
+// Write appends records to the same file, so records that refer to each other are kept together
+func (w *Writer) Write(records []Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file != nil && w.size >= w.maxSize {
+		err := w.rotate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if w.file == nil {
+		err := w.open()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range records {
+		err := w.write(&records[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write appends a single gzip member holding record to the current file
+func (w *Writer) write(record *Record) error {
+	var compressed bytes.Buffer
+	z := gzip.NewWriter(&compressed)
+	_, err := z.Write(record.marshal())
+	if err != nil {
+		return err
+	}
+
+	err = z.Close()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.file.Write(compressed.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if record.Capture != nil {
+		line, err := indexLine(record, w.name, w.size, int64(compressed.Len()))
+		if err != nil {
+			return err
+		}
+
+		w.index = append(w.index, line)
+	}
+
+	w.size += int64(compressed.Len())
+	return nil
+}
+
+func (w *Writer) open() error {
+	w.serial++
+	// https://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/#annex-c-informative-warc-file-size-and-name-recommendations
+	w.name = fmt.Sprintf("%v-%v-%05d-%v.warc.gz", w.prefix, time.Now().UTC().Format("20060102150405"), w.serial, w.host)
+
+	file, err := os.OpenFile(path.Join(w.dir, w.name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	w.file = file
+	w.size = 0
+	w.index = nil
+
+	var info strings.Builder
+	info.WriteString("format: WARC File Format 1.1\r\n")
+	info.WriteString("conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n")
+	for _, f := range w.info {
+		fmt.Fprintf(&info, "%v: %v\r\n", f.Name, f.Value)
+	}
+
+	return w.write(&Record{
+		Type:        TYPE_WARCINFO,
+		Date:        time.Now(),
+		ContentType: "application/warc-fields",
+		Fields:      []Field{{Name: "WARC-Filename", Value: w.name}},
+		Block:       []byte(info.String()),
+	})
+}
+
+// rotate closes the current file and writes its index
+func (w *Writer) rotate() error {
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return err
+	}
+
+	indexPath := path.Join(w.dir, strings.TrimSuffix(w.name, ".warc.gz")+".cdxj")
+	err = writeIndex(indexPath, w.index)
+	if err != nil {
+		return err
+	}
+
+	if w.rotated != nil {
+		w.rotated(path.Join(w.dir, w.name), indexPath)
+	}
+
+	return nil
+}
+
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	return w.rotate()
+}
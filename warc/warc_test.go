@@ -0,0 +1,166 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readMember decompresses the gzip member of a record at offset in a file
+func readMember(t *testing.T, file string, offset int64, length int64) string {
+	t.Helper()
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	z, err := gzip.NewReader(io.NewSectionReader(f, offset, length))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(record)
+}
+
+func TestRecordMarshal(t *testing.T) {
+	record := Record{
+		Type:        TYPE_RESPONSE,
+		TargetUri:   "http://a.com/",
+		Date:        time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		ContentType: "application/http; msgtype=response",
+		Fields:      []Field{{Name: "WARC-Payload-Digest", Value: Digest([]byte("body"))}},
+		Block:       []byte("block"),
+	}
+
+	marshaled := string(record.marshal())
+	if !strings.HasPrefix(marshaled, VERSION+"\r\n") || !strings.HasSuffix(marshaled, "\r\n\r\nblock\r\n\r\n") {
+		t.Errorf("marshal() = %q, want a WARC/1.1 record ending in its block", marshaled)
+	}
+
+	if !strings.HasPrefix(record.Id, "<urn:uuid:") {
+		t.Errorf("record id = %q, want a generated uuid", record.Id)
+	}
+
+	for _, field := range []string{
+		"WARC-Type: response",
+		"WARC-Date: 2024-05-01T00:00:00Z",
+		"WARC-Payload-Digest: " + Digest([]byte("body")),
+		"WARC-Block-Digest: " + Digest([]byte("block")),
+		"Content-Length: 5",
+	} {
+		if !strings.Contains(marshaled, field+"\r\n") {
+			t.Errorf("marshal() = %q, want it to contain %q", marshaled, field)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	dir := t.TempDir()
+
+	var rotated []string
+	// every write after the first starts a new file
+	w, err := NewWriter(dir, "test", "host", 1, []Field{{Name: "software", Value: "crawler"}}, func(files ...string) {
+		rotated = append(rotated, files...)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := func(u string) Record {
+		return Record{
+			Type:      TYPE_RESPONSE,
+			TargetUri: u,
+			Date:      time.Now(),
+			Block:     []byte("body of " + u),
+			Capture:   &Capture{Status: 200, Mime: "text/html", Digest: Digest([]byte(u))},
+		}
+	}
+
+	// records written together stay in the same file
+	err = w.Write([]Record{response("http://b.com/"), response("http://a.com/")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Write([]Record{response("http://c.com/")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(path.Join(dir, "test-*-host.warc.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || len(rotated) != 4 {
+		t.Fatalf("wrote %v and rotated %v, want 2 files with an index each", files, rotated)
+	}
+
+	index, err := os.ReadFile(strings.TrimSuffix(files[0], ".warc.gz") + ".cdxj")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(index)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "com,a)/ ") || !strings.HasPrefix(lines[1], "com,b)/ ") {
+		t.Fatalf("index = %q, want a sorted line for each response", index)
+	}
+
+	// each index line points at the gzip member of its record
+	for _, line := range lines {
+		var fields indexFields
+		err = json.Unmarshal([]byte(line[strings.Index(line, "{"):]), &fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		offset, _ := strconv.ParseInt(fields.Offset, 10, 64)
+		length, _ := strconv.ParseInt(fields.Length, 10, 64)
+		record := readMember(t, path.Join(dir, fields.Filename), offset, length)
+		if !strings.Contains(record, "WARC-Target-URI: "+fields.Url+"\r\n") {
+			t.Errorf("record at %v = %q, want the record of %v", offset, record, fields.Url)
+		}
+	}
+
+	// files start with a warcinfo record, and can be read as one gzip stream
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	z, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(contents, []byte(VERSION+"\r\nWARC-Type: warcinfo\r\n")) || !bytes.Contains(contents, []byte("software: crawler\r\n")) {
+		t.Errorf("file starts with %q, want a warcinfo record", contents[:min(len(contents), 100)])
+	}
+	if count := bytes.Count(contents, []byte(VERSION+"\r\n")); count != 3 {
+		t.Errorf("file has %v records, want the warcinfo record and 2 responses", count)
+	}
+}